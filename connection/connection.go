@@ -31,6 +31,7 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/backoff"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/proto"
 	"k8s.io/klog/v2"
 )
 
@@ -146,11 +147,20 @@ func WithOtelTracing() Option {
 	}
 }
 
+// WithLogPolicy changes which fields of the gRPC messages get logged.
+// The default is to log everything except secrets.
+func WithLogPolicy(policy protosanitizer.Policy) Option {
+	return func(o *options) {
+		o.logPolicy = policy
+	}
+}
+
 type options struct {
 	reconnect         func(context.Context) bool
 	timeout           time.Duration
 	metricsManager    metrics.CSIMetricsManager
 	enableOtelTracing bool
+	logPolicy         protosanitizer.Policy
 }
 
 // connect is the internal implementation of Connect. It has more options to enable testing.
@@ -179,7 +189,7 @@ func connect(
 		defer cancel()
 	}
 
-	interceptors := []grpc.UnaryClientInterceptor{LogGRPC}
+	interceptors := []grpc.UnaryClientInterceptor{LogGRPCWithPolicy(o.logPolicy)}
 	if o.metricsManager != nil {
 		interceptors = append(interceptors, ExtendedCSIMetricsManager{o.metricsManager}.RecordMetricsClientInterceptor)
 	}
@@ -260,10 +270,22 @@ func connect(
 
 // LogGRPC is gPRC unary interceptor for logging of CSI messages at level 5. It removes any secrets from the message.
 func LogGRPC(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return logGRPC(ctx, protosanitizer.Policy{}, method, req, reply, cc, invoker, opts...)
+}
+
+// LogGRPCWithPolicy returns a gRPC unary interceptor which behaves like LogGRPC,
+// except that the policy determines which fields of the messages get logged.
+func LogGRPCWithPolicy(policy protosanitizer.Policy) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return logGRPC(ctx, policy, method, req, reply, cc, invoker, opts...)
+	}
+}
+
+func logGRPC(ctx context.Context, policy protosanitizer.Policy, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
 	logger := klog.FromContext(ctx)
-	logger.V(5).Info("GRPC call", "method", method, "request", protosanitizer.StripSecrets(req))
+	logger.V(5).Info("GRPC call", "method", method, "request", policy.StripSecrets(req))
 	err := invoker(ctx, method, req, reply, cc, opts...)
-	cappedStr := protosanitizer.StripSecrets(reply).String()
+	cappedStr := policy.StripSecrets(reply).String()
 	if maxLogChar > 0 && len(cappedStr) > maxLogChar {
 		cappedStr = cappedStr[:maxLogChar] + fmt.Sprintf(" [response body too large, log capped to %d chars]", maxLogChar)
 	}
@@ -291,12 +313,8 @@ func (cmm ExtendedCSIMetricsManager) RecordMetricsClientInterceptor(
 	cc *grpc.ClientConn,
 	invoker grpc.UnaryInvoker,
 	opts ...grpc.CallOption) error {
-	start := time.Now()
-	err := invoker(ctx, method, req, reply, cc, opts...)
-	duration := time.Since(start)
-
 	var cmmBase metrics.CSIMetricsManager
-	cmmBase = cmm
+	cmmBase = cmm.CSIMetricsManager
 	if cmm.HaveAdditionalLabel(metrics.LabelMigrated) {
 		// record migration status
 		additionalInfo := ctx.Value(AdditionalInfoKey)
@@ -305,7 +323,7 @@ func (cmm ExtendedCSIMetricsManager) RecordMetricsClientInterceptor(
 			additionalInfoVal, ok := additionalInfo.(AdditionalInfo)
 			if !ok {
 				klog.FromContext(ctx).Error(nil, "Failed to record migrated status, cannot convert additional info", "additionalInfo", additionalInfo)
-				return err
+				return invoker(ctx, method, req, reply, cc, opts...)
 			}
 			migrated = additionalInfoVal.Migrated
 		}
@@ -317,11 +335,10 @@ func (cmm ExtendedCSIMetricsManager) RecordMetricsClientInterceptor(
 		}
 	}
 	// Record the default metric
-	cmmBase.RecordMetrics(
-		method,   /* operationName */
-		err,      /* operationErr */
-		duration, /* operationDuration */
-	)
+	done := startOperation(ctx, cmmBase, method /* operationName */)
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	done(err /* operationErr */)
+	recordMessageSizes(cmmBase, method, req, reply, err)
 
 	return err
 }
@@ -329,13 +346,42 @@ func (cmm ExtendedCSIMetricsManager) RecordMetricsClientInterceptor(
 // RecordMetricsServerInterceptor is a gPRC unary interceptor for recording metrics for CSI operations
 // in a gRCP server.
 func (cmm ExtendedCSIMetricsManager) RecordMetricsServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-	start := time.Now()
+	done := startOperation(ctx, cmm.CSIMetricsManager, info.FullMethod /* operationName */)
 	resp, err := handler(ctx, req)
-	duration := time.Since(start)
-	cmm.RecordMetrics(
-		info.FullMethod, /* operationName */
-		err,             /* operationErr */
-		duration,        /* operationDuration */
-	)
+	done(err /* operationErr */)
+	recordMessageSizes(cmm.CSIMetricsManager, info.FullMethod, req, resp, err)
 	return resp, err
 }
+
+// startOperation uses metrics.OperationStarter if the metrics manager
+// implements it. Otherwise it only records the completed operation, with
+// metrics.ContextRecorder if available.
+func startOperation(ctx context.Context, cmm metrics.CSIMetricsManager, method string) func(err error) {
+	if starter, ok := cmm.(metrics.OperationStarter); ok {
+		return starter.StartOperationWithContext(ctx, method)
+	}
+	start := time.Now()
+	return func(err error) {
+		if recorder, ok := cmm.(metrics.ContextRecorder); ok {
+			recorder.RecordMetricsWithContext(ctx, method, err, time.Since(start))
+			return
+		}
+		cmm.RecordMetrics(method, err, time.Since(start))
+	}
+}
+
+// recordMessageSizes records the sizes of protobuf messages if the metrics
+// manager implements metrics.MessageSizeRecorder. The response is only
+// recorded if the operation succeeded.
+func recordMessageSizes(cmm metrics.CSIMetricsManager, method string, req, resp interface{}, err error) {
+	recorder, ok := cmm.(metrics.MessageSizeRecorder)
+	if !ok {
+		return
+	}
+	request, _ := req.(proto.Message)
+	var response proto.Message
+	if err == nil {
+		response, _ = resp.(proto.Message)
+	}
+	recorder.RecordMessageSizes(method, request, response)
+}
@@ -31,6 +31,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -38,7 +39,9 @@ import (
 	"github.com/container-storage-interface/spec/lib/go/csi"
 
 	"github.com/kubernetes-csi/csi-lib-utils/metrics"
+	"github.com/kubernetes-csi/csi-lib-utils/protosanitizer"
 	"k8s.io/component-base/metrics/testutil"
+	"k8s.io/klog/v2"
 	"k8s.io/klog/v2/ktesting"
 	_ "k8s.io/klog/v2/ktesting/init"
 )
@@ -406,7 +409,7 @@ func TestConnectMetrics(t *testing.T) {
 			csi_sidecar_operations_seconds_count{driver_name="fake.csi.driver.io",grpc_status_code="Unimplemented",method_name="/csi.v1.Identity/GetPluginInfo"} 1
 			`,
 			ctx:         context.Background(),
-			cmm:         metrics.NewCSIMetricsManager("fake.csi.driver.io"),
+			cmm:         metrics.NewCSIMetricsManagerWithOptions("fake.csi.driver.io", metrics.WithInFlightMetrics()),
 			checkServer: true,
 		},
 		{
@@ -433,7 +436,7 @@ func TestConnectMetrics(t *testing.T) {
 			ctx: context.WithValue(context.Background(), AdditionalInfoKey, AdditionalInfo{
 				Migrated: "true",
 			}),
-			cmm:         metrics.NewCSIMetricsManagerWithOptions("fake.csi.driver.io", metrics.WithMigration()),
+			cmm:         metrics.NewCSIMetricsManagerWithOptions("fake.csi.driver.io", metrics.WithMigration(), metrics.WithInFlightMetrics()),
 			checkServer: false,
 		},
 	}
@@ -441,7 +444,10 @@ func TestConnectMetrics(t *testing.T) {
 		t.Logf("Running testcase %v", test.name)
 		tmp := tmpDir(t)
 		defer os.RemoveAll(tmp)
-		cmmServer := metrics.NewCSIMetricsManagerForPlugin("fake.csi.driver.io")
+		cmmServer := metrics.NewCSIMetricsManagerWithOptions("fake.csi.driver.io",
+			metrics.WithSubsystem(metrics.SubsystemPlugin),
+			metrics.WithInFlightMetrics(),
+		)
 		// We have to have a real implementation of the gRPC call, otherwise the metrics
 		// interceptor is not called. The CSI identity service is used because it's simple.
 		addr, stopServer := startServer(t, tmp, &csi.UnimplementedIdentityServer{}, nil, cmmServer)
@@ -470,7 +476,25 @@ func TestConnectMetrics(t *testing.T) {
 			}
 		}
 
+		expectedOperations := `# HELP csi_sidecar_operations_in_flight [ALPHA] Number of Container Storage Interface operations which were started and have not completed yet
+		# TYPE csi_sidecar_operations_in_flight gauge
+		csi_sidecar_operations_in_flight{driver_name="fake.csi.driver.io",method_name="/csi.v1.Identity/GetPluginInfo"} 0
+		# HELP csi_sidecar_operations_started_total [ALPHA] Total number of Container Storage Interface operations which were started
+		# TYPE csi_sidecar_operations_started_total counter
+		csi_sidecar_operations_started_total{driver_name="fake.csi.driver.io",method_name="/csi.v1.Identity/GetPluginInfo"} 1
+		`
+		if err := testutil.GatherAndCompare(
+			cmm.GetRegistry(), strings.NewReader(expectedOperations), "csi_sidecar_operations_in_flight", "csi_sidecar_operations_started_total"); err != nil {
+			t.Errorf("Expected client operation metrics not found -- %v", err)
+		}
+
 		if test.checkServer {
+			expectedOperations := strings.Replace(expectedOperations, "csi_sidecar", metrics.SubsystemPlugin, -1)
+			if err := testutil.GatherAndCompare(
+				cmmServer.GetRegistry(), strings.NewReader(expectedOperations), "csi_plugin_operations_in_flight", "csi_plugin_operations_started_total"); err != nil {
+				t.Errorf("Expected server operation metrics not found -- %v", err)
+			}
+
 			expectedMetrics := strings.Replace(test.expectedMetrics, "csi_sidecar", metrics.SubsystemPlugin, -1)
 			if err := testutil.GatherAndCompare(
 				cmmServer.GetRegistry(), strings.NewReader(expectedMetrics), "csi_plugin_operations_seconds"); err != nil {
@@ -484,6 +508,101 @@ func TestConnectMetrics(t *testing.T) {
 	}
 }
 
+// basicMetricsManager hides all optional interfaces of the wrapped
+// manager, like a mock which only implements CSIMetricsManager would.
+type basicMetricsManager struct {
+	metrics.CSIMetricsManager
+}
+
+func TestRecordMetricsWithBasicManager(t *testing.T) {
+	cmm := metrics.NewCSIMetricsManagerWithOptions("fake.csi.driver.io",
+		metrics.WithSubsystem(metrics.SubsystemPlugin),
+		metrics.WithInFlightMetrics(),
+	)
+	basic := ExtendedCSIMetricsManager{basicMetricsManager{cmm}}
+	_, isStarter := basic.CSIMetricsManager.(metrics.OperationStarter)
+	require.False(t, isStarter, "basic manager must not implement OperationStarter")
+	_, isRecorder := basic.CSIMetricsManager.(metrics.ContextRecorder)
+	require.False(t, isRecorder, "basic manager must not implement ContextRecorder")
+	_, isSizeRecorder := basic.CSIMetricsManager.(metrics.MessageSizeRecorder)
+	require.False(t, isSizeRecorder, "basic manager must not implement MessageSizeRecorder")
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/csi.v1.Identity/GetPluginInfo"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Unimplemented, "not implemented")
+	}
+	_, err := basic.RecordMetricsServerInterceptor(context.Background(), &csi.GetPluginInfoRequest{}, info, handler)
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+
+	vec, err := testutil.GetHistogramVecFromGatherer(cmm.GetRegistry(), "csi_plugin_operations_seconds", nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), vec.GetAggregatedSampleCount(), "recorded operations")
+	// Without OperationStarter, only completed operations get recorded.
+	assert.NoError(t, testutil.GatherAndCompare(cmm.GetRegistry(), strings.NewReader(""), "csi_plugin_operations_started_total"), "started operations")
+}
+
+type pluginInfoServer struct {
+	csi.UnimplementedIdentityServer
+}
+
+func (pluginInfoServer) GetPluginInfo(context.Context, *csi.GetPluginInfoRequest) (*csi.GetPluginInfoResponse, error) {
+	return &csi.GetPluginInfoResponse{Name: "fake.csi.driver.io"}, nil
+}
+
+func TestConnectMessageSizeMetrics(t *testing.T) {
+	tmp := tmpDir(t)
+	defer os.RemoveAll(tmp)
+	cmmServer := metrics.NewCSIMetricsManagerWithOptions("fake.csi.driver.io",
+		metrics.WithSubsystem(metrics.SubsystemPlugin),
+		metrics.WithMessageSizeMetrics([]float64{0, 100}),
+	)
+	addr, stopServer := startServer(t, tmp, pluginInfoServer{}, nil, cmmServer)
+	defer stopServer()
+
+	_, ctx := ktesting.NewTestContext(t)
+	cmm := metrics.NewCSIMetricsManagerWithOptions("fake.csi.driver.io",
+		metrics.WithMessageSizeMetrics([]float64{0, 100}),
+	)
+	conn, err := Connect(ctx, addr, cmm)
+	require.NoError(t, err, "connect")
+	defer conn.Close()
+	_, err = csi.NewIdentityClient(conn).GetPluginInfo(ctx, &csi.GetPluginInfoRequest{})
+	require.NoError(t, err, "GetPluginInfo")
+	_, err = csi.NewIdentityClient(conn).Probe(ctx, &csi.ProbeRequest{})
+	require.Error(t, err, "Probe")
+
+	// The response size of the failed Probe call is not recorded.
+	expectedMetrics := `# HELP csi_sidecar_request_size_bytes [ALPHA] Size of Container Storage Interface requests in protobuf encoding
+	# TYPE csi_sidecar_request_size_bytes histogram
+	csi_sidecar_request_size_bytes_bucket{driver_name="fake.csi.driver.io",method_name="/csi.v1.Identity/GetPluginInfo",le="0"} 1
+	csi_sidecar_request_size_bytes_bucket{driver_name="fake.csi.driver.io",method_name="/csi.v1.Identity/GetPluginInfo",le="100"} 1
+	csi_sidecar_request_size_bytes_bucket{driver_name="fake.csi.driver.io",method_name="/csi.v1.Identity/GetPluginInfo",le="+Inf"} 1
+	csi_sidecar_request_size_bytes_sum{driver_name="fake.csi.driver.io",method_name="/csi.v1.Identity/GetPluginInfo"} 0
+	csi_sidecar_request_size_bytes_count{driver_name="fake.csi.driver.io",method_name="/csi.v1.Identity/GetPluginInfo"} 1
+	csi_sidecar_request_size_bytes_bucket{driver_name="fake.csi.driver.io",method_name="/csi.v1.Identity/Probe",le="0"} 1
+	csi_sidecar_request_size_bytes_bucket{driver_name="fake.csi.driver.io",method_name="/csi.v1.Identity/Probe",le="100"} 1
+	csi_sidecar_request_size_bytes_bucket{driver_name="fake.csi.driver.io",method_name="/csi.v1.Identity/Probe",le="+Inf"} 1
+	csi_sidecar_request_size_bytes_sum{driver_name="fake.csi.driver.io",method_name="/csi.v1.Identity/Probe"} 0
+	csi_sidecar_request_size_bytes_count{driver_name="fake.csi.driver.io",method_name="/csi.v1.Identity/Probe"} 1
+	# HELP csi_sidecar_response_size_bytes [ALPHA] Size of successful Container Storage Interface responses in protobuf encoding
+	# TYPE csi_sidecar_response_size_bytes histogram
+	csi_sidecar_response_size_bytes_bucket{driver_name="fake.csi.driver.io",method_name="/csi.v1.Identity/GetPluginInfo",le="0"} 0
+	csi_sidecar_response_size_bytes_bucket{driver_name="fake.csi.driver.io",method_name="/csi.v1.Identity/GetPluginInfo",le="100"} 1
+	csi_sidecar_response_size_bytes_bucket{driver_name="fake.csi.driver.io",method_name="/csi.v1.Identity/GetPluginInfo",le="+Inf"} 1
+	csi_sidecar_response_size_bytes_sum{driver_name="fake.csi.driver.io",method_name="/csi.v1.Identity/GetPluginInfo"} 20
+	csi_sidecar_response_size_bytes_count{driver_name="fake.csi.driver.io",method_name="/csi.v1.Identity/GetPluginInfo"} 1
+	`
+	if err := testutil.GatherAndCompare(
+		cmm.GetRegistry(), strings.NewReader(expectedMetrics), "csi_sidecar_request_size_bytes", "csi_sidecar_response_size_bytes"); err != nil {
+		t.Errorf("Expected client metrics not found -- %v", err)
+	}
+	expectedMetrics = strings.Replace(expectedMetrics, "csi_sidecar", metrics.SubsystemPlugin, -1)
+	if err := testutil.GatherAndCompare(
+		cmmServer.GetRegistry(), strings.NewReader(expectedMetrics), "csi_plugin_request_size_bytes", "csi_plugin_response_size_bytes"); err != nil {
+		t.Errorf("Expected server metrics not found -- %v", err)
+	}
+}
+
 func verifyMetricsError(t *testing.T, err error, metricToIgnore string) error {
 	errStringLines := strings.Split(err.Error(), "\n")
 
@@ -524,3 +643,28 @@ func TestConnectWithOtelGrpcInterceptorTraces(t *testing.T) {
 	}
 
 }
+
+func TestLogGRPCWithPolicy(t *testing.T) {
+	policy, err := protosanitizer.NewAllowlistPolicy(&fieldmaskpb.FieldMask{Paths: []string{"name"}})
+	require.NoError(t, err)
+
+	logger := ktesting.NewLogger(t, ktesting.NewConfig(ktesting.Verbosity(5), ktesting.BufferLogs(true)))
+	ctx := klog.NewContext(context.Background(), logger)
+	req := &csi.CreateVolumeRequest{
+		Name:       "foo",
+		Parameters: map[string]string{"param": "value"},
+		Secrets:    map[string]string{"secret": "value"},
+	}
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return nil
+	}
+	err = LogGRPCWithPolicy(policy)(ctx, "/csi.v1.Controller/CreateVolume", req, &csi.CreateVolumeResponse{}, nil, invoker)
+	require.NoError(t, err)
+
+	underlier, ok := logger.GetSink().(ktesting.Underlier)
+	require.True(t, ok, "ktesting logger")
+	output := underlier.GetBuffer().String()
+	assert.Contains(t, output, `request="{\"name\":\"foo\"}"`)
+	assert.NotContains(t, output, "param")
+	assert.NotContains(t, output, "secret")
+}
@@ -18,17 +18,28 @@ package metrics
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"math"
+	"net"
 	"net/http"
 	"net/http/pprof"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 	"k8s.io/component-base/metrics"
+	"k8s.io/klog/v2"
 )
 
 const (
@@ -49,18 +60,63 @@ const (
 	labelGrpcStatusCode   = "grpc_status_code"
 	unknownCSIDriverName  = "unknown-driver"
 
+	// Values of grpc_status_code for errors without a gRPC status code
+	// or, with WithErrorClassification, when the context of the
+	// operation ended.
+	unknownNonGRPCErrorCode = "unknown-non-grpc"
+	clientTimeoutErrorCode  = "client-timeout"
+	clientCanceledErrorCode = "client-canceled"
+	connectionErrorCode     = "connection-error"
+
 	// LabelMigrated is the Label that indicate whether this is a CSI migration operation
 	LabelMigrated = "migrated"
 
+	// LabelErrorReason is the label for the reason of a google.rpc.ErrorInfo
+	// error detail, see WithErrorReasonLabel.
+	LabelErrorReason = "error_reason"
+
+	// OtherLabelValue replaces the values of a label once its limit is
+	// reached, see WithLabelCardinalityLimit.
+	OtherLabelValue = "other"
+
 	// CSI Operation Latency with status code total - Histogram Metric
 	operationsLatencyMetricName = "operations_seconds"
 	operationsLatencyHelp       = "Container Storage Interface operation duration with gRPC error code status total"
+
+	// CSI Operations currently in progress - Gauge Metric
+	operationsInFlightMetricName = "operations_in_flight"
+	operationsInFlightHelp       = "Number of Container Storage Interface operations which were started and have not completed yet"
+
+	// CSI Operations started - Counter Metric
+	operationsStartedMetricName = "operations_started_total"
+	operationsStartedHelp       = "Total number of Container Storage Interface operations which were started"
+
+	// CSI message sizes - Histogram Metrics
+	requestSizeMetricName  = "request_size_bytes"
+	requestSizeHelp        = "Size of Container Storage Interface requests in protobuf encoding"
+	responseSizeMetricName = "response_size_bytes"
+	responseSizeHelp       = "Size of successful Container Storage Interface responses in protobuf encoding"
+
+	// Label values replaced by OtherLabelValue - Counter Metric
+	droppedLabelValuesMetricName = "dropped_label_values_total"
+	droppedLabelValuesHelp       = "Total number of label values which were replaced with \"other\" because the label had too many distinct values"
+	labelLabelName               = "label_name"
 )
 
 var (
 	operationsLatencyBuckets = []float64{.1, .25, .5, 1, 2.5, 5, 10, 15, 25, 50, 120, 300, 600}
+	// messageSizeBuckets range from 128 bytes to 8MiB, twice the default
+	// maximum message size of gRPC.
+	messageSizeBuckets = prometheus.ExponentialBuckets(128, 4, 9)
 )
 
+// DefaultLatencyBuckets returns a copy of the default buckets of the
+// operations_seconds histogram. Other histograms of durations can use
+// them to be consistent with it.
+func DefaultLatencyBuckets() []float64 {
+	return slices.Clone(operationsLatencyBuckets)
+}
+
 // CSIMetricsManager exposes functions for recording metrics for CSI operations.
 type CSIMetricsManager interface {
 	// GetRegistry() returns the metrics.KubeRegistry used by this metrics manager.
@@ -114,6 +170,65 @@ type CSIMetricsManager interface {
 	RegisterPprofToServer(s Server)
 }
 
+// ContextRecorder is implemented by the metrics managers of this package,
+// including the ones returned by WithLabelValues. Like OperationStarter, it
+// is optional. Callers should check for it with a type assertion and fall
+// back to RecordMetrics.
+type ContextRecorder interface {
+	// RecordMetricsWithContext is like RecordMetrics. In addition, if the
+	// context contains a sampled OpenTelemetry span, its trace and span ID
+	// are attached as exemplar to the operations_seconds observation. This
+	// links slow operations to their traces, for example in Grafana.
+	// Exemplars are only exposed to scrapers which negotiate the
+	// OpenMetrics or Prometheus protobuf format.
+	RecordMetricsWithContext(
+		ctx context.Context,
+		operationName string,
+		operationErr error,
+		operationDuration time.Duration)
+}
+
+// OperationStarter is implemented by the metrics managers of this package,
+// including the ones returned by WithLabelValues. It is not part of
+// CSIMetricsManager because other implementations of that interface, for
+// example mocks, do not need to provide it. Callers should check for it
+// with a type assertion and fall back to RecordMetrics.
+type OperationStarter interface {
+	// StartOperation must be called when a CSI operation starts. If
+	// enabled via WithInFlightMetrics, it increments the
+	// operations_started_total counter and the operations_in_flight
+	// gauge of the operation. The returned function must be called when
+	// the operation completes. It decrements the gauge and records the operation like RecordMetrics, with the
+	// duration since StartOperation. Calling it more than once has no
+	// effect.
+	//
+	// Operations which never complete stay in operations_in_flight.
+	// An alert on min_over_time(..._operations_in_flight[10m]) > 0
+	// catches operations that have been stuck for ten minutes.
+	//
+	// operationName - Name of the CSI operation.
+	StartOperation(operationName string) func(operationErr error)
+
+	// StartOperationWithContext is like StartOperation and records the
+	// completed operation like RecordMetricsWithContext.
+	StartOperationWithContext(ctx context.Context, operationName string) func(operationErr error)
+}
+
+// MessageSizeRecorder is implemented by the metrics managers of this
+// package, including the ones returned by WithLabelValues. Like
+// OperationStarter, it is optional. Callers should check for it with a
+// type assertion and skip recording the sizes without it.
+type MessageSizeRecorder interface {
+	// RecordMessageSizes records the encoded size of the request and
+	// response of a CSI operation in the request_size_bytes and
+	// response_size_bytes histograms. It does nothing unless enabled
+	// via WithMessageSizeMetrics. Nil messages are skipped, so the
+	// response should be nil when the operation failed.
+	//
+	// operationName - Name of the CSI operation.
+	RecordMessageSizes(operationName string, request, response proto.Message)
+}
+
 // Server represents any type that could serve HTTP requests for the metrics
 // endpoint.
 type Server interface {
@@ -192,6 +307,158 @@ func WithProcessStartTime(registerProcessStartTime bool) MetricsManagerOption {
 	}
 }
 
+// WithLatencyBuckets overrides the buckets of the operations_seconds histogram.
+// The default buckets range from 0.1s to 600s. The upper bounds get sorted
+// and duplicates are removed. Nil restores the default. An empty list or one
+// which contains NaN is logged as an error and also restores the default.
+func WithLatencyBuckets(buckets []float64) MetricsManagerOption {
+	return func(cmm *csiMetricsManager) {
+		if buckets == nil {
+			cmm.latencyBuckets = nil
+			return
+		}
+		if len(buckets) == 0 || slices.ContainsFunc(buckets, math.IsNaN) {
+			klog.Background().Error(nil, "Invalid latency buckets, using the defaults", "buckets", fmt.Sprint(buckets))
+			cmm.latencyBuckets = nil
+			return
+		}
+		cmm.latencyBuckets = sortedBuckets(buckets)
+	}
+}
+
+// sortedBuckets returns the upper bounds sorted in increasing order,
+// without duplicates and NaN, as required by Prometheus histograms.
+func sortedBuckets(buckets []float64) []float64 {
+	sorted := slices.DeleteFunc(slices.Clone(buckets), math.IsNaN)
+	slices.Sort(sorted)
+	return slices.Compact(sorted)
+}
+
+// NativeHistogramOptions configures Prometheus native histograms, also
+// known as sparse histograms. See prometheus.HistogramOpts for details.
+type NativeHistogramOptions struct {
+	// BucketFactor is the maximum growth factor from one bucket to
+	// the next. It must be larger than 1. The default is 1.1, so each
+	// bucket is at most 10% wider than the previous one. Other values
+	// <= 1 are logged as an error and replaced by the default.
+	BucketFactor float64
+	// MaxBucketNumber limits the number of buckets. When it is reached,
+	// the resolution gets reduced. The default is 160.
+	MaxBucketNumber uint32
+	// MinResetDuration, if non-zero, causes the histogram to be reset
+	// instead of reducing the resolution when MaxBucketNumber is
+	// reached and the last reset was at least that long ago.
+	MinResetDuration time.Duration
+}
+
+// WithNativeHistograms enables native histograms in addition to the classic
+// buckets. Native histograms resolve very short and very long operations
+// alike in a single metric, without having to choose buckets. They are
+// only exposed to scrapers which negotiate the Prometheus protobuf format,
+// others continue to see the classic buckets.
+func WithNativeHistograms(options NativeHistogramOptions) MetricsManagerOption {
+	return func(cmm *csiMetricsManager) {
+		if options.BucketFactor != 0 && !(options.BucketFactor > 1) {
+			klog.Background().Error(nil, "Invalid native histogram bucket factor, using the default", "bucketFactor", fmt.Sprint(options.BucketFactor))
+			options.BucketFactor = 0
+		}
+		if options.BucketFactor == 0 {
+			options.BucketFactor = 1.1
+		}
+		if options.MaxBucketNumber == 0 {
+			options.MaxBucketNumber = 160
+		}
+		cmm.nativeHistograms = &options
+	}
+}
+
+// WithMessageSizeMetrics enables the request_size_bytes and
+// response_size_bytes histograms, with upper bounds in bytes. Nil selects
+// the default buckets, which range from 128 bytes to 8MiB. The upper
+// bounds get sorted, duplicates and NaN are removed. If nothing is left,
+// the error is logged and the default buckets are used. Computing the
+// size of a message is not free, therefore the histograms are disabled
+// by default.
+func WithMessageSizeMetrics(buckets []float64) MetricsManagerOption {
+	return func(cmm *csiMetricsManager) {
+		if buckets == nil {
+			cmm.messageSizeBuckets = messageSizeBuckets
+			return
+		}
+		sorted := sortedBuckets(buckets)
+		if len(sorted) == 0 {
+			klog.Background().Error(nil, "Invalid message size buckets, using the defaults", "buckets", fmt.Sprint(buckets))
+			sorted = messageSizeBuckets
+		}
+		cmm.messageSizeBuckets = sorted
+	}
+}
+
+// WithInFlightMetrics enables the operations_started_total counter and the
+// operations_in_flight gauge. They are updated by OperationStarter, which
+// the interceptors in the connection package use. Without this option,
+// only completed operations are recorded, as before the metrics were
+// added.
+func WithInFlightMetrics() MetricsManagerOption {
+	return func(cmm *csiMetricsManager) {
+		cmm.inFlightMetrics = true
+	}
+}
+
+// WithLabelCardinalityLimit limits the number of distinct values of each
+// label defined via WithLabelNames. Once the limit is reached, further
+// values passed to WithLabelValues are replaced with OtherLabelValue. This
+// protects Prometheus against callers which put unbounded values like
+// volume IDs into a label. The dropped_label_values_total counter tracks
+// how often that happens and the first replacement per label gets logged.
+//
+// The limit also applies to the label enabled by WithErrorReasonLabel.
+//
+// Zero, the default, disables the limit.
+func WithLabelCardinalityLimit(limit int) MetricsManagerOption {
+	return func(cmm *csiMetricsManager) {
+		cmm.labelCardinalityLimit = limit
+	}
+}
+
+// WithErrorClassification enables a more detailed grpc_status_code label.
+// Without it, the label contains the gRPC status code of an error or
+// "unknown-non-grpc" for all other errors. With it, errors get classified
+// as follows:
+//   - "client-timeout": the context of the operation reached its deadline,
+//     regardless of whether the error is context.DeadlineExceeded or a
+//     gRPC status with code DeadlineExceeded. A DeadlineExceeded status
+//     returned by a server while the context is still active keeps
+//     "DeadlineExceeded".
+//   - "client-canceled": the same for a canceled context.
+//   - "connection-error": a non-gRPC error from the network layer.
+//   - the gRPC status code for all other gRPC errors.
+//   - "unknown-non-grpc" for all remaining errors. This includes
+//     context.DeadlineExceeded and context.Canceled while the context
+//     of the operation is still active, because then they come from
+//     some other context.
+//
+// The context is only known when using RecordMetricsWithContext or
+// StartOperationWithContext, as the interceptors in the connection
+// package do.
+func WithErrorClassification() MetricsManagerOption {
+	return func(cmm *csiMetricsManager) {
+		cmm.classifyErrors = true
+	}
+}
+
+// WithErrorReasonLabel adds the error_reason label to operations_seconds.
+// It contains the reason of the first google.rpc.ErrorInfo detail of a
+// gRPC status error and is empty if there is none. Drivers can use that
+// to distinguish failures with the same gRPC status code. The reason is
+// meant to be a constant like "QUOTA_EXCEEDED", so it does not
+// increase the cardinality much.
+func WithErrorReasonLabel() MetricsManagerOption {
+	return func(cmm *csiMetricsManager) {
+		cmm.errorReasonLabel = true
+	}
+}
+
 // WithCustomRegistry allow user to use custom pre-created registry instead of a new created one.
 func WithCustomRegistry(registry metrics.KubeRegistry) MetricsManagerOption {
 	return func(cmm *csiMetricsManager) {
@@ -251,40 +518,207 @@ func NewCSIMetricsManagerWithOptions(driverName string, options ...MetricsManage
 	}
 
 	labels := []string{labelCSIDriverName, labelCSIOperationName, labelGrpcStatusCode}
+	if cmm.errorReasonLabel {
+		labels = append(labels, LabelErrorReason)
+	}
 	labels = append(labels, cmm.additionalLabelNames...)
 	for _, label := range cmm.additionalLabels {
 		labels = append(labels, label.name)
 	}
-	cmm.csiOperationsLatencyMetric = metrics.NewHistogramVec(
-		&metrics.HistogramOpts{
-			Subsystem:      cmm.subsystem,
-			Name:           operationsLatencyMetricName,
-			Help:           operationsLatencyHelp,
-			Buckets:        operationsLatencyBuckets,
-			StabilityLevel: cmm.stabilityLevel,
-		},
-		labels,
-	)
+	if cmm.latencyBuckets == nil {
+		cmm.latencyBuckets = operationsLatencyBuckets
+	}
+	cmm.csiOperationsLatencyMetric = cmm.newHistogramVec(operationsLatencyMetricName, operationsLatencyHelp, cmm.latencyBuckets, labels)
+
+	// The varying labels are not known when an operation starts,
+	// therefore only the fixed ones are used. The same applies to
+	// the message sizes and SLOs.
+	inFlightLabels := []string{labelCSIDriverName, labelCSIOperationName}
+	for _, label := range cmm.additionalLabels {
+		inFlightLabels = append(inFlightLabels, label.name)
+	}
+	if cmm.inFlightMetrics {
+		cmm.csiOperationsInFlightMetric = metrics.NewGaugeVec(
+			&metrics.GaugeOpts{
+				Subsystem:      cmm.subsystem,
+				Name:           operationsInFlightMetricName,
+				Help:           operationsInFlightHelp,
+				StabilityLevel: cmm.stabilityLevel,
+			},
+			inFlightLabels,
+		)
+		cmm.csiOperationsStartedMetric = metrics.NewCounterVec(
+			&metrics.CounterOpts{
+				Subsystem:      cmm.subsystem,
+				Name:           operationsStartedMetricName,
+				Help:           operationsStartedHelp,
+				StabilityLevel: cmm.stabilityLevel,
+			},
+			inFlightLabels,
+		)
+		cmm.registry.MustRegister(cmm.csiOperationsInFlightMetric, cmm.csiOperationsStartedMetric)
+	}
+	if cmm.messageSizeBuckets != nil {
+		cmm.csiRequestSizeMetric = cmm.newHistogramVec(requestSizeMetricName, requestSizeHelp, cmm.messageSizeBuckets, inFlightLabels)
+		cmm.csiResponseSizeMetric = cmm.newHistogramVec(responseSizeMetricName, responseSizeHelp, cmm.messageSizeBuckets, inFlightLabels)
+	}
+	if cmm.labelCardinalityLimit > 0 {
+		cmm.labelValues = map[string]map[string]struct{}{}
+		cmm.labelLimitLogged = map[string]bool{}
+		cmm.csiDroppedLabelValuesMetric = metrics.NewCounterVec(
+			&metrics.CounterOpts{
+				Subsystem:      cmm.subsystem,
+				Name:           droppedLabelValuesMetricName,
+				Help:           droppedLabelValuesHelp,
+				StabilityLevel: cmm.stabilityLevel,
+			},
+			[]string{labelCSIDriverName, labelLabelName},
+		)
+		cmm.registry.MustRegister(cmm.csiDroppedLabelValuesMetric)
+	}
+	if cmm.slos != nil {
+		cmm.csiSLOTotalMetric = metrics.NewCounterVec(
+			&metrics.CounterOpts{
+				Subsystem:      cmm.subsystem,
+				Name:           sloTotalMetricName,
+				Help:           sloTotalHelp,
+				StabilityLevel: cmm.stabilityLevel,
+			},
+			inFlightLabels,
+		)
+		cmm.csiSLOGoodMetric = metrics.NewCounterVec(
+			&metrics.CounterOpts{
+				Subsystem:      cmm.subsystem,
+				Name:           sloGoodMetricName,
+				Help:           sloGoodHelp,
+				StabilityLevel: cmm.stabilityLevel,
+			},
+			inFlightLabels,
+		)
+		cmm.registry.MustRegister(cmm.csiSLOTotalMetric, cmm.csiSLOGoodMetric)
+	}
+	if cmm.meterProvider != nil {
+		cmm.otel = cmm.newOtelInstruments(labels, inFlightLabels)
+	}
 	cmm.SetDriverName(driverName)
-	cmm.registerMetrics()
 	cmm.gatherers = prometheus.Gatherers{
 		cmm.GetRegistry(),
 	}
 	return &cmm
 }
 
-var _ CSIMetricsManager = &csiMetricsManager{}
+var (
+	_ CSIMetricsManager   = &csiMetricsManager{}
+	_ ContextRecorder     = &csiMetricsManager{}
+	_ ContextRecorder     = &csiMetricsManagerWithValues{}
+	_ MessageSizeRecorder = &csiMetricsManager{}
+	_ MessageSizeRecorder = &csiMetricsManagerWithValues{}
+	_ OperationStarter    = &csiMetricsManager{}
+	_ OperationStarter    = &csiMetricsManagerWithValues{}
+)
 
 type csiMetricsManager struct {
-	registry                   metrics.KubeRegistry
-	subsystem                  string
-	stabilityLevel             metrics.StabilityLevel
-	driverName                 string
-	additionalLabelNames       []string
-	additionalLabels           []label
-	gatherers                  prometheus.Gatherers
-	csiOperationsLatencyMetric *metrics.HistogramVec
-	registerProcessStartTime   bool
+	registry                    metrics.KubeRegistry
+	subsystem                   string
+	stabilityLevel              metrics.StabilityLevel
+	driverName                  string
+	additionalLabelNames        []string
+	additionalLabels            []label
+	gatherers                   prometheus.Gatherers
+	csiOperationsLatencyMetric  histogramVec
+	csiOperationsInFlightMetric *metrics.GaugeVec
+	csiOperationsStartedMetric  *metrics.CounterVec
+	csiRequestSizeMetric        histogramVec
+	csiResponseSizeMetric       histogramVec
+	csiSLOTotalMetric           *metrics.CounterVec
+	csiSLOGoodMetric            *metrics.CounterVec
+	csiDroppedLabelValuesMetric *metrics.CounterVec
+	registerProcessStartTime    bool
+	latencyBuckets              []float64
+	nativeHistograms            *NativeHistogramOptions
+	messageSizeBuckets          []float64
+	slos                        map[string]SLO
+	meterProvider               metric.MeterProvider
+	otel                        *otelInstruments
+	labelCardinalityLimit       int
+	inFlightMetrics             bool
+	classifyErrors              bool
+	errorReasonLabel            bool
+
+	// labelValuesMutex protects labelValues, the values seen so far
+	// for each label, and labelLimitLogged, the labels for which a
+	// replacement was logged, when a cardinality limit is set.
+	labelValuesMutex sync.Mutex
+	labelValues      map[string]map[string]struct{}
+	labelLimitLogged map[string]bool
+}
+
+// histogramVec is implemented by metrics.HistogramVec and nativeHistogramVec.
+type histogramVec interface {
+	WithLabelValues(lvs ...string) metrics.ObserverMetric
+}
+
+// nativeHistogramVec adapts a prometheus.HistogramVec to histogramVec.
+type nativeHistogramVec struct {
+	*prometheus.HistogramVec
+}
+
+func (v nativeHistogramVec) WithLabelValues(lvs ...string) metrics.ObserverMetric {
+	return v.HistogramVec.WithLabelValues(lvs...)
+}
+
+// newHistogramVec creates and registers a histogram, with native histogram
+// buckets if enabled.
+func (cmm *csiMetricsManager) newHistogramVec(name, help string, buckets []float64, labels []string) histogramVec {
+	if cmm.nativeHistograms == nil {
+		vec := metrics.NewHistogramVec(
+			&metrics.HistogramOpts{
+				Subsystem:      cmm.subsystem,
+				Name:           name,
+				Help:           help,
+				Buckets:        buckets,
+				StabilityLevel: cmm.stabilityLevel,
+			},
+			labels,
+		)
+		cmm.registry.MustRegister(vec)
+		return vec
+	}
+
+	// metrics.HistogramOpts has no per-metric native histogram options,
+	// so the Prometheus histogram gets registered directly, with the
+	// same stability annotation in the help text.
+	vec := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem:                       cmm.subsystem,
+			Name:                            name,
+			Help:                            fmt.Sprintf("[%v] %v", cmm.stabilityLevel, help),
+			Buckets:                         buckets,
+			NativeHistogramBucketFactor:     cmm.nativeHistograms.BucketFactor,
+			NativeHistogramMaxBucketNumber:  cmm.nativeHistograms.MaxBucketNumber,
+			NativeHistogramMinResetDuration: cmm.nativeHistograms.MinResetDuration,
+		},
+		labels,
+	)
+	cmm.registry.RawMustRegister(vec)
+	return nativeHistogramVec{vec}
+}
+
+// observeWithExemplar attaches the trace and span ID of a sampled span in
+// the context as exemplar, like metrics.Histogram.WithContext does for
+// histograms without labels.
+func observeWithExemplar(ctx context.Context, observer metrics.ObserverMetric, value float64) {
+	if exemplarObserver, ok := observer.(prometheus.ExemplarObserver); ok {
+		spanCtx := trace.SpanContextFromContext(ctx)
+		if spanCtx.IsValid() && spanCtx.IsSampled() {
+			exemplarObserver.ObserveWithExemplar(value, prometheus.Labels{
+				"trace_id": spanCtx.TraceID().String(),
+				"span_id":  spanCtx.SpanID().String(),
+			})
+			return
+		}
+	}
+	observer.Observe(value)
 }
 
 type label struct {
@@ -300,23 +734,116 @@ func (cmm *csiMetricsManager) RecordMetrics(
 	operationName string,
 	operationErr error,
 	operationDuration time.Duration) {
-	cmm.recordMetricsWithLabels(operationName, operationErr, operationDuration, nil)
+	cmm.recordMetricsWithLabels(context.Background(), operationName, operationErr, operationDuration, nil)
+}
+
+// RecordMetricsWithContext implements ContextRecorder.RecordMetricsWithContext.
+func (cmm *csiMetricsManager) RecordMetricsWithContext(
+	ctx context.Context,
+	operationName string,
+	operationErr error,
+	operationDuration time.Duration) {
+	cmm.recordMetricsWithLabels(ctx, operationName, operationErr, operationDuration, nil)
 }
 
 // recordMetricsWithLabels is the internal implementation of RecordMetrics.
 func (cmm *csiMetricsManager) recordMetricsWithLabels(
+	ctx context.Context,
 	operationName string,
 	operationErr error,
 	operationDuration time.Duration,
 	labelValues map[string]string) {
-	values := []string{cmm.driverName, operationName, getErrorCode(operationErr)}
+	values := []string{cmm.driverName, operationName, cmm.errorCode(ctx, operationErr)}
+	if cmm.errorReasonLabel {
+		values = append(values, cmm.limitLabelValue(LabelErrorReason, getErrorReason(operationErr)))
+	}
 	for _, name := range cmm.additionalLabelNames {
 		values = append(values, labelValues[name])
 	}
 	for _, label := range cmm.additionalLabels {
 		values = append(values, label.value)
 	}
-	cmm.csiOperationsLatencyMetric.WithLabelValues(values...).Observe(operationDuration.Seconds())
+	observeWithExemplar(ctx, cmm.csiOperationsLatencyMetric.WithLabelValues(values...), operationDuration.Seconds())
+	cmm.recordSLO(ctx, operationName, operationErr, operationDuration)
+	if cmm.otel != nil {
+		cmm.otel.latency.Record(ctx, operationDuration.Seconds(), attributes(cmm.otel.latencyLabels, values))
+	}
+}
+
+// StartOperation implements OperationStarter.StartOperation.
+func (cmm *csiMetricsManager) StartOperation(operationName string) func(operationErr error) {
+	return cmm.startOperationWithLabels(context.Background(), operationName, nil)
+}
+
+// StartOperationWithContext implements OperationStarter.StartOperationWithContext.
+func (cmm *csiMetricsManager) StartOperationWithContext(ctx context.Context, operationName string) func(operationErr error) {
+	return cmm.startOperationWithLabels(ctx, operationName, nil)
+}
+
+// startOperationWithLabels is the internal implementation of StartOperation.
+func (cmm *csiMetricsManager) startOperationWithLabels(ctx context.Context, operationName string, labelValues map[string]string) func(operationErr error) {
+	start := time.Now()
+	// The values are determined once, so the same gauge gets decremented
+	// even if SetDriverName is called while the operation runs.
+	values := cmm.fixedLabelValues(operationName)
+	var inFlight metrics.GaugeMetric
+	var otelAttributes metric.MeasurementOption
+	if cmm.inFlightMetrics {
+		cmm.csiOperationsStartedMetric.WithLabelValues(values...).Inc()
+		inFlight = cmm.csiOperationsInFlightMetric.WithLabelValues(values...)
+		inFlight.Inc()
+		if cmm.otel != nil {
+			otelAttributes = attributes(cmm.otel.inFlightLabels, values)
+			cmm.otel.started.Add(ctx, 1, otelAttributes)
+			cmm.otel.inFlight.Add(ctx, 1, otelAttributes)
+		}
+	}
+
+	var once sync.Once
+	return func(operationErr error) {
+		once.Do(func() {
+			if inFlight != nil {
+				inFlight.Dec()
+				if cmm.otel != nil {
+					cmm.otel.inFlight.Add(ctx, -1, otelAttributes)
+				}
+			}
+			cmm.recordMetricsWithLabels(ctx, operationName, operationErr, time.Since(start), labelValues)
+		})
+	}
+}
+
+// fixedLabelValues returns the values for the labels which do not depend
+// on the outcome of an operation, i.e. the driver name, the operation name
+// and the values set via WithLabels.
+func (cmm *csiMetricsManager) fixedLabelValues(operationName string) []string {
+	values := []string{cmm.driverName, operationName}
+	for _, label := range cmm.additionalLabels {
+		values = append(values, label.value)
+	}
+	return values
+}
+
+// RecordMessageSizes implements MessageSizeRecorder.RecordMessageSizes.
+func (cmm *csiMetricsManager) RecordMessageSizes(operationName string, request, response proto.Message) {
+	if cmm.messageSizeBuckets == nil {
+		return
+	}
+	values := cmm.fixedLabelValues(operationName)
+	if request != nil {
+		size := proto.Size(request)
+		cmm.csiRequestSizeMetric.WithLabelValues(values...).Observe(float64(size))
+		if cmm.otel != nil {
+			cmm.otel.requestSize.Record(context.Background(), int64(size), attributes(cmm.otel.inFlightLabels, values))
+		}
+	}
+	if response != nil {
+		size := proto.Size(response)
+		cmm.csiResponseSizeMetric.WithLabelValues(values...).Observe(float64(size))
+		if cmm.otel != nil {
+			cmm.otel.responseSize.Record(context.Background(), int64(size), attributes(cmm.otel.inFlightLabels, values))
+		}
+	}
 }
 
 type csiMetricsManagerWithValues struct {
@@ -358,11 +885,39 @@ func (cmmv *csiMetricsManagerWithValues) WithLabelValues(labels map[string]strin
 		if v, ok := extended.additionalValues[name]; ok {
 			return nil, fmt.Errorf("label %q already has value %q", name, v)
 		}
-		extended.additionalValues[name] = value
+		extended.additionalValues[name] = cmmv.limitLabelValue(name, value)
 	}
 	return extended, nil
 }
 
+// limitLabelValue returns the value unchanged unless the label already
+// has as many distinct values as allowed by WithLabelCardinalityLimit.
+func (cmm *csiMetricsManager) limitLabelValue(name, value string) string {
+	if cmm.labelCardinalityLimit <= 0 {
+		return value
+	}
+	cmm.labelValuesMutex.Lock()
+	defer cmm.labelValuesMutex.Unlock()
+	values, ok := cmm.labelValues[name]
+	if !ok {
+		values = map[string]struct{}{}
+		cmm.labelValues[name] = values
+	}
+	if _, ok := values[value]; ok {
+		return value
+	}
+	if len(values) < cmm.labelCardinalityLimit {
+		values[value] = struct{}{}
+		return value
+	}
+	if !cmm.labelLimitLogged[name] {
+		cmm.labelLimitLogged[name] = true
+		klog.Background().Info("Label has too many distinct values, replacing further values", "label", name, "value", value, "limit", cmm.labelCardinalityLimit, "replacement", OtherLabelValue)
+	}
+	cmm.csiDroppedLabelValuesMetric.WithLabelValues(cmm.driverName, name).Inc()
+	return OtherLabelValue
+}
+
 func (cmm *csiMetricsManager) HaveAdditionalLabel(name string) bool {
 	for _, n := range cmm.additionalLabelNames {
 		if n == name {
@@ -385,7 +940,26 @@ func (cmmv *csiMetricsManagerWithValues) RecordMetrics(
 	operationName string,
 	operationErr error,
 	operationDuration time.Duration) {
-	cmmv.recordMetricsWithLabels(operationName, operationErr, operationDuration, cmmv.additionalValues)
+	cmmv.recordMetricsWithLabels(context.Background(), operationName, operationErr, operationDuration, cmmv.additionalValues)
+}
+
+// RecordMetricsWithContext passes the stored values to the implementation.
+func (cmmv *csiMetricsManagerWithValues) RecordMetricsWithContext(
+	ctx context.Context,
+	operationName string,
+	operationErr error,
+	operationDuration time.Duration) {
+	cmmv.recordMetricsWithLabels(ctx, operationName, operationErr, operationDuration, cmmv.additionalValues)
+}
+
+// StartOperation passes the stored values to the implementation.
+func (cmmv *csiMetricsManagerWithValues) StartOperation(operationName string) func(operationErr error) {
+	return cmmv.startOperationWithLabels(context.Background(), operationName, cmmv.additionalValues)
+}
+
+// StartOperationWithContext passes the stored values to the implementation.
+func (cmmv *csiMetricsManagerWithValues) StartOperationWithContext(ctx context.Context, operationName string) func(operationErr error) {
+	return cmmv.startOperationWithLabels(ctx, operationName, cmmv.additionalValues)
 }
 
 // SetDriverName is called to update the CSI driver name. This should be done
@@ -448,11 +1022,11 @@ func VerifyMetricsMatch(expectedMetrics, actualMetrics string, metricToIgnore st
 	return nil
 }
 
-func (cmm *csiMetricsManager) registerMetrics() {
-	cmm.registry.MustRegister(cmm.csiOperationsLatencyMetric)
-}
-
-func getErrorCode(err error) string {
+// ErrorCode returns the value of the grpc_status_code label for an
+// error: "OK" for nil, the gRPC status code for gRPC errors and
+// "unknown-non-grpc" for all other errors. Code which records related
+// metrics can use it to stay consistent with CSIMetricsManager.
+func ErrorCode(err error) string {
 	if err == nil {
 		return codes.OK.String()
 	}
@@ -461,8 +1035,64 @@ func getErrorCode(err error) string {
 	if !ok {
 		// This is not gRPC error. The operation must have failed before gRPC
 		// method was called, otherwise we would get gRPC error.
-		return "unknown-non-grpc"
+		return unknownNonGRPCErrorCode
 	}
 
 	return st.Code().String()
 }
+
+// errorCode returns the value of the grpc_status_code label.
+func (cmm *csiMetricsManager) errorCode(ctx context.Context, err error) string {
+	if !cmm.classifyErrors {
+		return ErrorCode(err)
+	}
+	return classifyError(ctx, err)
+}
+
+// classifyError implements the classification described for
+// WithErrorClassification.
+func classifyError(ctx context.Context, err error) string {
+	if err == nil {
+		return codes.OK.String()
+	}
+
+	if st, ok := status.FromError(err); ok {
+		code := st.Code()
+		if (code == codes.DeadlineExceeded || code == codes.Canceled) && ctx.Err() != nil {
+			return contextErrorCode(ctx.Err())
+		}
+		return code.String()
+	}
+
+	var opErr *net.OpError
+	switch {
+	case (errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)) && ctx.Err() != nil:
+		return contextErrorCode(ctx.Err())
+	case errors.As(err, &opErr), errors.Is(err, net.ErrClosed):
+		return connectionErrorCode
+	default:
+		return unknownNonGRPCErrorCode
+	}
+}
+
+func contextErrorCode(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return clientTimeoutErrorCode
+	}
+	return clientCanceledErrorCode
+}
+
+// getErrorReason returns the reason of the first ErrorInfo detail of a
+// gRPC status error.
+func getErrorReason(err error) string {
+	st, ok := status.FromError(err)
+	if !ok {
+		return ""
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info.GetReason()
+		}
+	}
+	return ""
+}
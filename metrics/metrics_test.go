@@ -17,15 +17,23 @@ limitations under the License.
 package metrics
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
+	"math"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 	"k8s.io/component-base/metrics"
 	"k8s.io/component-base/metrics/testutil"
 )
@@ -623,3 +631,530 @@ func TestProcessStartTimeMetricExist(t *testing.T) {
 
 	t.Fatalf("Metrics does not contain %v. Scraped content: %v", ProcessStartTimeMetric, metricsFamilies)
 }
+
+func TestLatencyBuckets(t *testing.T) {
+	// Arrange
+	cmm := NewCSIMetricsManagerWithOptions(
+		"fake.csi.driver.io", /* driverName */
+		WithLatencyBuckets([]float64{0.001, 0.01, 1800}),
+	)
+
+	// Act
+	cmm.RecordMetrics(
+		"/csi.v1.Node/NodeGetVolumeStats", /* operationName */
+		nil,                               /* operationErr */
+		5*time.Millisecond /* operationDuration */)
+	cmm.RecordMetrics(
+		"/csi.v1.Controller/CreateVolume", /* operationName */
+		nil,                               /* operationErr */
+		20*time.Minute /* operationDuration */)
+
+	// Assert
+	expectedMetrics := `# HELP csi_sidecar_operations_seconds [ALPHA] Container Storage Interface operation duration with gRPC error code status total
+		# TYPE csi_sidecar_operations_seconds histogram
+		csi_sidecar_operations_seconds_bucket{driver_name="fake.csi.driver.io",grpc_status_code="OK",method_name="/csi.v1.Controller/CreateVolume",le="0.001"} 0
+		csi_sidecar_operations_seconds_bucket{driver_name="fake.csi.driver.io",grpc_status_code="OK",method_name="/csi.v1.Controller/CreateVolume",le="0.01"} 0
+		csi_sidecar_operations_seconds_bucket{driver_name="fake.csi.driver.io",grpc_status_code="OK",method_name="/csi.v1.Controller/CreateVolume",le="1800"} 1
+		csi_sidecar_operations_seconds_bucket{driver_name="fake.csi.driver.io",grpc_status_code="OK",method_name="/csi.v1.Controller/CreateVolume",le="+Inf"} 1
+		csi_sidecar_operations_seconds_sum{driver_name="fake.csi.driver.io",grpc_status_code="OK",method_name="/csi.v1.Controller/CreateVolume"} 1200
+		csi_sidecar_operations_seconds_count{driver_name="fake.csi.driver.io",grpc_status_code="OK",method_name="/csi.v1.Controller/CreateVolume"} 1
+		csi_sidecar_operations_seconds_bucket{driver_name="fake.csi.driver.io",grpc_status_code="OK",method_name="/csi.v1.Node/NodeGetVolumeStats",le="0.001"} 0
+		csi_sidecar_operations_seconds_bucket{driver_name="fake.csi.driver.io",grpc_status_code="OK",method_name="/csi.v1.Node/NodeGetVolumeStats",le="0.01"} 1
+		csi_sidecar_operations_seconds_bucket{driver_name="fake.csi.driver.io",grpc_status_code="OK",method_name="/csi.v1.Node/NodeGetVolumeStats",le="1800"} 1
+		csi_sidecar_operations_seconds_bucket{driver_name="fake.csi.driver.io",grpc_status_code="OK",method_name="/csi.v1.Node/NodeGetVolumeStats",le="+Inf"} 1
+		csi_sidecar_operations_seconds_sum{driver_name="fake.csi.driver.io",grpc_status_code="OK",method_name="/csi.v1.Node/NodeGetVolumeStats"} 0.005
+		csi_sidecar_operations_seconds_count{driver_name="fake.csi.driver.io",grpc_status_code="OK",method_name="/csi.v1.Node/NodeGetVolumeStats"} 1
+	`
+
+	if err := testutil.GatherAndCompare(
+		cmm.GetRegistry(), strings.NewReader(expectedMetrics), SidecarOperationMetric); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInvalidLatencyBuckets(t *testing.T) {
+	for name, tc := range map[string]struct {
+		buckets         []float64
+		expectedBuckets []float64
+	}{
+		"nil": {
+			expectedBuckets: operationsLatencyBuckets,
+		},
+		"empty": {
+			buckets:         []float64{},
+			expectedBuckets: operationsLatencyBuckets,
+		},
+		"NaN": {
+			buckets:         []float64{1, math.NaN()},
+			expectedBuckets: operationsLatencyBuckets,
+		},
+		"unsorted with duplicates": {
+			buckets:         []float64{10, 1, 10, 0.5},
+			expectedBuckets: []float64{0.5, 1, 10},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Registration of the histogram panics for invalid buckets.
+			cmm := NewCSIMetricsManagerWithOptions(
+				"fake.csi.driver.io", /* driverName */
+				WithLatencyBuckets(tc.buckets),
+			)
+			cmm.RecordMetrics("myOperation", nil, time.Second)
+
+			buckets := cmm.(*csiMetricsManager).latencyBuckets
+			if fmt.Sprint(buckets) != fmt.Sprint(tc.expectedBuckets) {
+				t.Errorf("expected buckets %v, got %v", tc.expectedBuckets, buckets)
+			}
+		})
+	}
+}
+
+func TestInvalidNativeHistogramBucketFactor(t *testing.T) {
+	for _, factor := range []float64{1, 0.5, -1, math.NaN()} {
+		t.Run(fmt.Sprint(factor), func(t *testing.T) {
+			cmm := NewCSIMetricsManagerWithOptions(
+				"fake.csi.driver.io", /* driverName */
+				WithNativeHistograms(NativeHistogramOptions{BucketFactor: factor}),
+			)
+			cmm.RecordMetrics("myOperation", nil, time.Second)
+
+			if actual := cmm.(*csiMetricsManager).nativeHistograms.BucketFactor; actual != 1.1 {
+				t.Errorf("expected default bucket factor 1.1, got %v", actual)
+			}
+			vec, err := testutil.GetHistogramVecFromGatherer(cmm.GetRegistry(), SidecarOperationMetric, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count := vec.GetAggregatedSampleCount(); count != 1 {
+				t.Errorf("expected 1 sample, got %d", count)
+			}
+		})
+	}
+}
+
+func TestNativeHistograms(t *testing.T) {
+	// Arrange
+	cmm := NewCSIMetricsManagerWithOptions(
+		"fake.csi.driver.io", /* driverName */
+		WithNativeHistograms(NativeHistogramOptions{}),
+	)
+
+	// Act
+	cmm.RecordMetrics(
+		"/csi.v1.Node/NodeGetVolumeStats", /* operationName */
+		nil,                               /* operationErr */
+		5*time.Millisecond /* operationDuration */)
+	cmm.RecordMetrics(
+		"/csi.v1.Node/NodeGetVolumeStats", /* operationName */
+		nil,                               /* operationErr */
+		30*time.Minute /* operationDuration */)
+
+	// Assert
+	metricsFamilies, err := cmm.GetRegistry().Gather()
+	if err != nil {
+		t.Fatalf("Error fetching metrics: %v", err)
+	}
+	for _, metricsFamily := range metricsFamilies {
+		if metricsFamily.GetName() != SidecarOperationMetric {
+			continue
+		}
+		if help := metricsFamily.GetHelp(); help != "[ALPHA] "+operationsLatencyHelp {
+			t.Errorf("unexpected help text %q", help)
+		}
+		histogram := metricsFamily.GetMetric()[0].GetHistogram()
+		if histogram.GetSampleCount() != 2 {
+			t.Errorf("expected 2 samples, got %d", histogram.GetSampleCount())
+		}
+		if len(histogram.GetBucket()) != len(operationsLatencyBuckets) {
+			t.Errorf("expected %d classic buckets, got %d", len(operationsLatencyBuckets), len(histogram.GetBucket()))
+		}
+		if histogram.GetSchema() != 3 {
+			t.Errorf("expected schema 3 for bucket factor 1.1, got %d", histogram.GetSchema())
+		}
+		if len(histogram.GetPositiveDelta()) != 2 {
+			t.Errorf("expected two populated native buckets, got %v", histogram.GetPositiveDelta())
+		}
+		return
+	}
+	t.Fatalf("Metrics does not contain %v. Scraped content: %v", SidecarOperationMetric, metricsFamilies)
+}
+
+func TestStartOperation(t *testing.T) {
+	// Arrange
+	cmm := NewCSIMetricsManagerWithOptions(
+		"fake.csi.driver.io", /* driverName */
+		WithLabels(map[string]string{"c": "333"}),
+		WithInFlightMetrics(),
+	)
+	inFlightMetric := "csi_sidecar_" + operationsInFlightMetricName
+	startedMetric := "csi_sidecar_" + operationsStartedMetricName
+
+	// Act
+	done := cmm.(OperationStarter).StartOperation("/csi.v1.Controller/CreateVolume" /* operationName */)
+
+	// Assert
+	expectedMetrics := `# HELP csi_sidecar_operations_in_flight [ALPHA] Number of Container Storage Interface operations which were started and have not completed yet
+		# TYPE csi_sidecar_operations_in_flight gauge
+		csi_sidecar_operations_in_flight{c="333",driver_name="fake.csi.driver.io",method_name="/csi.v1.Controller/CreateVolume"} 1
+		# HELP csi_sidecar_operations_started_total [ALPHA] Total number of Container Storage Interface operations which were started
+		# TYPE csi_sidecar_operations_started_total counter
+		csi_sidecar_operations_started_total{c="333",driver_name="fake.csi.driver.io",method_name="/csi.v1.Controller/CreateVolume"} 1
+	`
+	if err := testutil.GatherAndCompare(
+		cmm.GetRegistry(), strings.NewReader(expectedMetrics), inFlightMetric, startedMetric); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	done(status.Error(codes.Internal, "fake error") /* operationErr */)
+	done(nil /* operationErr */)
+
+	// Assert
+	expectedMetrics = `# HELP csi_sidecar_operations_in_flight [ALPHA] Number of Container Storage Interface operations which were started and have not completed yet
+		# TYPE csi_sidecar_operations_in_flight gauge
+		csi_sidecar_operations_in_flight{c="333",driver_name="fake.csi.driver.io",method_name="/csi.v1.Controller/CreateVolume"} 0
+		# HELP csi_sidecar_operations_started_total [ALPHA] Total number of Container Storage Interface operations which were started
+		# TYPE csi_sidecar_operations_started_total counter
+		csi_sidecar_operations_started_total{c="333",driver_name="fake.csi.driver.io",method_name="/csi.v1.Controller/CreateVolume"} 1
+	`
+	if err := testutil.GatherAndCompare(
+		cmm.GetRegistry(), strings.NewReader(expectedMetrics), inFlightMetric, startedMetric); err != nil {
+		t.Fatal(err)
+	}
+	vec, err := testutil.GetHistogramVecFromGatherer(cmm.GetRegistry(), SidecarOperationMetric, map[string]string{"grpc_status_code": "Internal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := vec.GetAggregatedSampleCount(); count != 1 {
+		t.Errorf("expected the operation to be recorded once with its first error, got %d samples", count)
+	}
+}
+
+func TestStartOperationWithoutInFlightMetrics(t *testing.T) {
+	// Arrange
+	cmm := NewCSIMetricsManagerWithOptions(
+		"fake.csi.driver.io", /* driverName */
+	)
+
+	// Act
+	done := cmm.(OperationStarter).StartOperation("/csi.v1.Controller/CreateVolume" /* operationName */)
+	done(nil /* operationErr */)
+
+	// Assert
+	if err := testutil.GatherAndCompare(
+		cmm.GetRegistry(), strings.NewReader(""), "csi_sidecar_"+operationsInFlightMetricName, "csi_sidecar_"+operationsStartedMetricName); err != nil {
+		t.Fatal(err)
+	}
+	vec, err := testutil.GetHistogramVecFromGatherer(cmm.GetRegistry(), SidecarOperationMetric, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := vec.GetAggregatedSampleCount(); count != 1 {
+		t.Errorf("expected the operation to be recorded, got %d samples", count)
+	}
+}
+
+func TestRecordMetricsWithContext(t *testing.T) {
+	// Arrange
+	cmm := NewCSIMetricsManagerWithOptions(
+		"fake.csi.driver.io", /* driverName */
+		WithLatencyBuckets([]float64{1, 10}),
+	)
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	sampledCtx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+	notSampledCtx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+
+	// Act
+	cmm.(ContextRecorder).RecordMetricsWithContext(
+		sampledCtx,
+		"/csi.v1.Controller/CreateVolume", /* operationName */
+		nil,                               /* operationErr */
+		5*time.Second /* operationDuration */)
+	cmm.(ContextRecorder).RecordMetricsWithContext(
+		notSampledCtx,
+		"/csi.v1.Controller/CreateVolume", /* operationName */
+		nil,                               /* operationErr */
+		500*time.Millisecond /* operationDuration */)
+
+	// Assert
+	metricsFamilies, err := cmm.GetRegistry().Gather()
+	if err != nil {
+		t.Fatalf("Error fetching metrics: %v", err)
+	}
+	for _, metricsFamily := range metricsFamilies {
+		if metricsFamily.GetName() != SidecarOperationMetric {
+			continue
+		}
+		histogram := metricsFamily.GetMetric()[0].GetHistogram()
+		if histogram.GetSampleCount() != 2 {
+			t.Errorf("expected 2 samples, got %d", histogram.GetSampleCount())
+		}
+		buckets := histogram.GetBucket()
+		if exemplar := buckets[0].GetExemplar(); exemplar != nil {
+			t.Errorf("expected no exemplar for the unsampled span, got %v", exemplar)
+		}
+		exemplar := buckets[1].GetExemplar()
+		if exemplar == nil {
+			t.Fatal("expected an exemplar for the sampled span")
+		}
+		labels := map[string]string{}
+		for _, label := range exemplar.GetLabel() {
+			labels[label.GetName()] = label.GetValue()
+		}
+		expectedLabels := map[string]string{"trace_id": traceID.String(), "span_id": spanID.String()}
+		if fmt.Sprint(labels) != fmt.Sprint(expectedLabels) {
+			t.Errorf("expected exemplar labels %v, got %v", expectedLabels, labels)
+		}
+		if exemplar.GetValue() != 5 {
+			t.Errorf("expected exemplar value 5, got %v", exemplar.GetValue())
+		}
+		return
+	}
+	t.Fatalf("Metrics does not contain %v. Scraped content: %v", SidecarOperationMetric, metricsFamilies)
+}
+
+func TestRecordMessageSizes(t *testing.T) {
+	// Arrange
+	disabled := NewCSIMetricsManagerWithOptions("fake.csi.driver.io" /* driverName */)
+	cmm := NewCSIMetricsManagerWithOptions(
+		"fake.csi.driver.io", /* driverName */
+		WithMessageSizeMetrics([]float64{4, 16}),
+	)
+	request := wrapperspb.String("hello")          // 7 bytes
+	response := wrapperspb.Bytes(make([]byte, 20)) // 22 bytes
+
+	// Act
+	disabled.(MessageSizeRecorder).RecordMessageSizes("myOperation", request, response)
+	cmm.(MessageSizeRecorder).RecordMessageSizes("myOperation", request, response)
+	cmm.(MessageSizeRecorder).RecordMessageSizes("myOperation", request, nil)
+
+	// Assert
+	expectedMetrics := `# HELP csi_sidecar_request_size_bytes [ALPHA] Size of Container Storage Interface requests in protobuf encoding
+		# TYPE csi_sidecar_request_size_bytes histogram
+		csi_sidecar_request_size_bytes_bucket{driver_name="fake.csi.driver.io",method_name="myOperation",le="4"} 0
+		csi_sidecar_request_size_bytes_bucket{driver_name="fake.csi.driver.io",method_name="myOperation",le="16"} 2
+		csi_sidecar_request_size_bytes_bucket{driver_name="fake.csi.driver.io",method_name="myOperation",le="+Inf"} 2
+		csi_sidecar_request_size_bytes_sum{driver_name="fake.csi.driver.io",method_name="myOperation"} 14
+		csi_sidecar_request_size_bytes_count{driver_name="fake.csi.driver.io",method_name="myOperation"} 2
+		# HELP csi_sidecar_response_size_bytes [ALPHA] Size of successful Container Storage Interface responses in protobuf encoding
+		# TYPE csi_sidecar_response_size_bytes histogram
+		csi_sidecar_response_size_bytes_bucket{driver_name="fake.csi.driver.io",method_name="myOperation",le="4"} 0
+		csi_sidecar_response_size_bytes_bucket{driver_name="fake.csi.driver.io",method_name="myOperation",le="16"} 0
+		csi_sidecar_response_size_bytes_bucket{driver_name="fake.csi.driver.io",method_name="myOperation",le="+Inf"} 1
+		csi_sidecar_response_size_bytes_sum{driver_name="fake.csi.driver.io",method_name="myOperation"} 22
+		csi_sidecar_response_size_bytes_count{driver_name="fake.csi.driver.io",method_name="myOperation"} 1
+	`
+	if err := testutil.GatherAndCompare(
+		cmm.GetRegistry(), strings.NewReader(expectedMetrics), "csi_sidecar_request_size_bytes", "csi_sidecar_response_size_bytes"); err != nil {
+		t.Fatal(err)
+	}
+	if err := testutil.GatherAndCompare(
+		disabled.GetRegistry(), strings.NewReader(""), "csi_sidecar_request_size_bytes", "csi_sidecar_response_size_bytes"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInvalidMessageSizeBuckets(t *testing.T) {
+	for name, tc := range map[string]struct {
+		buckets         []float64
+		expectedBuckets []float64
+	}{
+		"nil": {
+			expectedBuckets: messageSizeBuckets,
+		},
+		"empty": {
+			buckets:         []float64{},
+			expectedBuckets: messageSizeBuckets,
+		},
+		"only NaN": {
+			buckets:         []float64{math.NaN()},
+			expectedBuckets: messageSizeBuckets,
+		},
+		"unsorted with duplicates and NaN": {
+			buckets:         []float64{100, 10, math.NaN(), 100},
+			expectedBuckets: []float64{10, 100},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cmm := NewCSIMetricsManagerWithOptions(
+				"fake.csi.driver.io", /* driverName */
+				WithMessageSizeMetrics(tc.buckets),
+			)
+			// Recording panicked for unsorted buckets.
+			cmm.(MessageSizeRecorder).RecordMessageSizes("myOperation", wrapperspb.String("hello"), nil)
+
+			buckets := cmm.(*csiMetricsManager).messageSizeBuckets
+			if fmt.Sprint(buckets) != fmt.Sprint(tc.expectedBuckets) {
+				t.Errorf("expected buckets %v, got %v", tc.expectedBuckets, buckets)
+			}
+		})
+	}
+}
+
+func TestLabelCardinalityLimit(t *testing.T) {
+	// Arrange
+	cmm := NewCSIMetricsManagerWithOptions(
+		"fake.csi.driver.io", /* driverName */
+		WithLabelNames("a", "b"),
+		WithLabelCardinalityLimit(2),
+	)
+
+	// Act
+	for _, value := range []string{"1", "2", "3", "4", "1"} {
+		cmmv, err := cmm.WithLabelValues(map[string]string{"a": value, "b": "x"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		cmmv.RecordMetrics(
+			"myOperation", /* operationName */
+			nil,           /* operationErr */
+			time.Second /* operationDuration */)
+	}
+
+	// Assert
+	expectedMetrics := `# HELP csi_sidecar_dropped_label_values_total [ALPHA] Total number of label values which were replaced with "other" because the label had too many distinct values
+		# TYPE csi_sidecar_dropped_label_values_total counter
+		csi_sidecar_dropped_label_values_total{driver_name="fake.csi.driver.io",label_name="a"} 2
+	`
+	if err := testutil.GatherAndCompare(
+		cmm.GetRegistry(), strings.NewReader(expectedMetrics), "csi_sidecar_dropped_label_values_total"); err != nil {
+		t.Fatal(err)
+	}
+	for value, expectedCount := range map[string]uint64{"1": 2, "2": 1, "3": 0, "4": 0, OtherLabelValue: 2} {
+		vec, err := testutil.GetHistogramVecFromGatherer(cmm.GetRegistry(), SidecarOperationMetric, map[string]string{"a": value})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count := vec.GetAggregatedSampleCount(); count != expectedCount {
+			t.Errorf("expected %d samples with a=%q, got %d", expectedCount, value, count)
+		}
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now())
+	defer cancelExpired()
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	testcases := map[string]struct {
+		ctx        context.Context
+		err        error
+		expectCode string
+	}{
+		"success": {
+			ctx:        context.Background(),
+			expectCode: "OK",
+		},
+		"gRPC error": {
+			ctx:        context.Background(),
+			err:        status.Error(codes.NotFound, "not found"),
+			expectCode: "NotFound",
+		},
+		"server deadline exceeded": {
+			ctx:        context.Background(),
+			err:        status.Error(codes.DeadlineExceeded, "storage system timed out"),
+			expectCode: "DeadlineExceeded",
+		},
+		"client timeout": {
+			ctx:        expired,
+			err:        status.Error(codes.DeadlineExceeded, "context deadline exceeded"),
+			expectCode: "client-timeout",
+		},
+		"client timeout without gRPC": {
+			ctx:        expired,
+			err:        fmt.Errorf("waiting for connection: %w", context.DeadlineExceeded),
+			expectCode: "client-timeout",
+		},
+		"client canceled": {
+			ctx:        canceled,
+			err:        status.Error(codes.Canceled, "context canceled"),
+			expectCode: "client-canceled",
+		},
+		"client canceled without gRPC": {
+			ctx:        canceled,
+			err:        context.Canceled,
+			expectCode: "client-canceled",
+		},
+		"canceled without canceled context": {
+			ctx:        context.Background(),
+			err:        context.Canceled,
+			expectCode: "unknown-non-grpc",
+		},
+		"deadline exceeded without expired context": {
+			ctx:        context.Background(),
+			err:        fmt.Errorf("waiting for connection: %w", context.DeadlineExceeded),
+			expectCode: "unknown-non-grpc",
+		},
+		"connection error": {
+			ctx:        context.Background(),
+			err:        &net.OpError{Op: "dial", Net: "unix", Err: errors.New("connection refused")},
+			expectCode: "connection-error",
+		},
+		"other error": {
+			ctx:        context.Background(),
+			err:        errors.New("fake error"),
+			expectCode: "unknown-non-grpc",
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			if code := classifyError(tc.ctx, tc.err); code != tc.expectCode {
+				t.Errorf("expected %q, got %q", tc.expectCode, code)
+			}
+		})
+	}
+}
+
+func TestErrorReasonLabel(t *testing.T) {
+	// Arrange
+	cmm := NewCSIMetricsManagerWithOptions(
+		"fake.csi.driver.io", /* driverName */
+		WithErrorClassification(),
+		WithErrorReasonLabel(),
+		WithLatencyBuckets([]float64{1}),
+	)
+	st, err := status.New(codes.ResourceExhausted, "quota exceeded").WithDetails(&errdetails.ErrorInfo{
+		Reason: "QUOTA_EXCEEDED",
+		Domain: "fake.csi.driver.io",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Act
+	cmm.RecordMetrics(
+		"myOperation", /* operationName */
+		st.Err(),      /* operationErr */
+		time.Second /* operationDuration */)
+	cmm.RecordMetrics(
+		"myOperation",     /* operationName */
+		io.ErrShortBuffer, /* operationErr */
+		time.Second /* operationDuration */)
+
+	// Assert
+	expectedMetrics := `# HELP csi_sidecar_operations_seconds [ALPHA] Container Storage Interface operation duration with gRPC error code status total
+		# TYPE csi_sidecar_operations_seconds histogram
+		csi_sidecar_operations_seconds_bucket{driver_name="fake.csi.driver.io",error_reason="",grpc_status_code="unknown-non-grpc",method_name="myOperation",le="1"} 1
+		csi_sidecar_operations_seconds_bucket{driver_name="fake.csi.driver.io",error_reason="",grpc_status_code="unknown-non-grpc",method_name="myOperation",le="+Inf"} 1
+		csi_sidecar_operations_seconds_sum{driver_name="fake.csi.driver.io",error_reason="",grpc_status_code="unknown-non-grpc",method_name="myOperation"} 1
+		csi_sidecar_operations_seconds_count{driver_name="fake.csi.driver.io",error_reason="",grpc_status_code="unknown-non-grpc",method_name="myOperation"} 1
+		csi_sidecar_operations_seconds_bucket{driver_name="fake.csi.driver.io",error_reason="QUOTA_EXCEEDED",grpc_status_code="ResourceExhausted",method_name="myOperation",le="1"} 1
+		csi_sidecar_operations_seconds_bucket{driver_name="fake.csi.driver.io",error_reason="QUOTA_EXCEEDED",grpc_status_code="ResourceExhausted",method_name="myOperation",le="+Inf"} 1
+		csi_sidecar_operations_seconds_sum{driver_name="fake.csi.driver.io",error_reason="QUOTA_EXCEEDED",grpc_status_code="ResourceExhausted",method_name="myOperation"} 1
+		csi_sidecar_operations_seconds_count{driver_name="fake.csi.driver.io",error_reason="QUOTA_EXCEEDED",grpc_status_code="ResourceExhausted",method_name="myOperation"} 1
+	`
+	if err := testutil.GatherAndCompare(
+		cmm.GetRegistry(), strings.NewReader(expectedMetrics), SidecarOperationMetric); err != nil {
+		t.Fatal(err)
+	}
+}
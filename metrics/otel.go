@@ -0,0 +1,131 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// meterName identifies this package as the source of the OpenTelemetry
+// instruments.
+const meterName = "github.com/kubernetes-csi/csi-lib-utils/metrics"
+
+// WithMeterProvider emits the CSI operation metrics also through the
+// OpenTelemetry meter provider, in addition to the Prometheus registry.
+// The provider determines how the metrics get exported, for example to
+// an OpenTelemetry collector via OTLP. The instruments have the same
+// names, labels and histogram buckets as their Prometheus counterparts,
+// so dashboards work for both.
+//
+// Nil, the default, disables OpenTelemetry metrics.
+func WithMeterProvider(provider metric.MeterProvider) MetricsManagerOption {
+	return func(cmm *csiMetricsManager) {
+		cmm.meterProvider = provider
+	}
+}
+
+// otelInstruments mirrors the Prometheus metrics of a csiMetricsManager.
+type otelInstruments struct {
+	latency        metric.Float64Histogram
+	inFlight       metric.Int64UpDownCounter
+	started        metric.Int64Counter
+	requestSize    metric.Int64Histogram
+	responseSize   metric.Int64Histogram
+	sloTotal       metric.Int64Counter
+	sloGood        metric.Int64Counter
+	latencyLabels  []string
+	inFlightLabels []string
+}
+
+// newOtelInstruments creates the instruments. Errors are passed to the
+// global OpenTelemetry error handler. The instruments returned together
+// with an error are still usable, so they are kept.
+func (cmm *csiMetricsManager) newOtelInstruments(latencyLabels, inFlightLabels []string) *otelInstruments {
+	meter := cmm.meterProvider.Meter(meterName)
+	prefix := cmm.subsystem + "_"
+	instruments := &otelInstruments{
+		latencyLabels:  latencyLabels,
+		inFlightLabels: inFlightLabels,
+	}
+	var err error
+	instruments.latency, err = meter.Float64Histogram(prefix+operationsLatencyMetricName,
+		metric.WithDescription(operationsLatencyHelp),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(cmm.latencyBuckets...),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+	if cmm.inFlightMetrics {
+		instruments.inFlight, err = meter.Int64UpDownCounter(prefix+operationsInFlightMetricName,
+			metric.WithDescription(operationsInFlightHelp),
+		)
+		if err != nil {
+			otel.Handle(err)
+		}
+		instruments.started, err = meter.Int64Counter(prefix+operationsStartedMetricName,
+			metric.WithDescription(operationsStartedHelp),
+		)
+		if err != nil {
+			otel.Handle(err)
+		}
+	}
+	if cmm.messageSizeBuckets != nil {
+		instruments.requestSize, err = meter.Int64Histogram(prefix+requestSizeMetricName,
+			metric.WithDescription(requestSizeHelp),
+			metric.WithUnit("By"),
+			metric.WithExplicitBucketBoundaries(cmm.messageSizeBuckets...),
+		)
+		if err != nil {
+			otel.Handle(err)
+		}
+		instruments.responseSize, err = meter.Int64Histogram(prefix+responseSizeMetricName,
+			metric.WithDescription(responseSizeHelp),
+			metric.WithUnit("By"),
+			metric.WithExplicitBucketBoundaries(cmm.messageSizeBuckets...),
+		)
+		if err != nil {
+			otel.Handle(err)
+		}
+	}
+	if cmm.slos != nil {
+		instruments.sloTotal, err = meter.Int64Counter(prefix+sloTotalMetricName,
+			metric.WithDescription(sloTotalHelp),
+		)
+		if err != nil {
+			otel.Handle(err)
+		}
+		instruments.sloGood, err = meter.Int64Counter(prefix+sloGoodMetricName,
+			metric.WithDescription(sloGoodHelp),
+		)
+		if err != nil {
+			otel.Handle(err)
+		}
+	}
+	return instruments
+}
+
+// attributes pairs label names with the values of a Prometheus sample.
+func attributes(names, values []string) metric.MeasurementOption {
+	kvs := make([]attribute.KeyValue, 0, len(names))
+	for i, name := range names {
+		kvs = append(kvs, attribute.String(name, values[i]))
+	}
+	return metric.WithAttributeSet(attribute.NewSet(kvs...))
+}
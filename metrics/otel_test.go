@@ -0,0 +1,161 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+// recordingMeterProvider records all measurements as
+// "<instrument>{<attributes>} <value>" lines.
+type recordingMeterProvider struct {
+	noop.MeterProvider
+
+	mutex        sync.Mutex
+	buckets      map[string][]float64
+	measurements []string
+}
+
+func (p *recordingMeterProvider) Meter(string, ...metric.MeterOption) metric.Meter {
+	return recordingMeter{provider: p}
+}
+
+func (p *recordingMeterProvider) record(name string, attributes attribute.Set, value any) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.measurements = append(p.measurements, fmt.Sprintf("%s{%s} %v", name, attributes.Encoded(attribute.DefaultEncoder()), value))
+}
+
+type recordingMeter struct {
+	noop.Meter
+	provider *recordingMeterProvider
+}
+
+func (m recordingMeter) Float64Histogram(name string, options ...metric.Float64HistogramOption) (metric.Float64Histogram, error) {
+	m.provider.mutex.Lock()
+	defer m.provider.mutex.Unlock()
+	if m.provider.buckets == nil {
+		m.provider.buckets = map[string][]float64{}
+	}
+	m.provider.buckets[name] = metric.NewFloat64HistogramConfig(options...).ExplicitBucketBoundaries()
+	return recordingFloat64Histogram{name: name, provider: m.provider}, nil
+}
+
+func (m recordingMeter) Int64Counter(name string, _ ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	return recordingInt64Counter{name: name, provider: m.provider}, nil
+}
+
+func (m recordingMeter) Int64UpDownCounter(name string, _ ...metric.Int64UpDownCounterOption) (metric.Int64UpDownCounter, error) {
+	return recordingInt64UpDownCounter{name: name, provider: m.provider}, nil
+}
+
+type recordingFloat64Histogram struct {
+	noop.Float64Histogram
+	name     string
+	provider *recordingMeterProvider
+}
+
+func (h recordingFloat64Histogram) Record(_ context.Context, value float64, options ...metric.RecordOption) {
+	h.provider.record(h.name, metric.NewRecordConfig(options).Attributes(), value)
+}
+
+type recordingInt64Counter struct {
+	noop.Int64Counter
+	name     string
+	provider *recordingMeterProvider
+}
+
+func (c recordingInt64Counter) Add(_ context.Context, value int64, options ...metric.AddOption) {
+	c.provider.record(c.name, metric.NewAddConfig(options).Attributes(), value)
+}
+
+type recordingInt64UpDownCounter struct {
+	noop.Int64UpDownCounter
+	name     string
+	provider *recordingMeterProvider
+}
+
+func (c recordingInt64UpDownCounter) Add(_ context.Context, value int64, options ...metric.AddOption) {
+	c.provider.record(c.name, metric.NewAddConfig(options).Attributes(), value)
+}
+
+func TestMeterProvider(t *testing.T) {
+	// Arrange
+	provider := &recordingMeterProvider{}
+	cmm := NewCSIMetricsManagerWithOptions(
+		"fake.csi.driver.io", /* driverName */
+		WithLabelNames("a"),
+		WithLabels(map[string]string{"c": "333"}),
+		WithLatencyBuckets([]float64{1, 10}),
+		WithMeterProvider(provider),
+		WithInFlightMetrics(),
+	)
+
+	// Act
+	cmmv, err := cmm.WithLabelValues(map[string]string{"a": "111"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	done := cmmv.(OperationStarter).StartOperation("/csi.v1.Controller/CreateVolume" /* operationName */)
+	done(status.Error(codes.Internal, "fake error") /* operationErr */)
+	cmm.RecordMetrics(
+		"myOperation", /* operationName */
+		nil,           /* operationErr */
+		20*time.Second /* operationDuration */)
+
+	// Assert
+	expectedBuckets := []float64{1, 10}
+	if buckets := provider.buckets[SidecarOperationMetric]; fmt.Sprint(buckets) != fmt.Sprint(expectedBuckets) {
+		t.Errorf("expected buckets %v, got %v", expectedBuckets, buckets)
+	}
+	if len(provider.measurements) != 5 {
+		t.Fatalf("expected 5 measurements, got:\n%s", strings.Join(provider.measurements, "\n"))
+	}
+	// The duration of the operation varies and is not checked.
+	provider.measurements[3] = provider.measurements[3][:strings.LastIndex(provider.measurements[3], " ")]
+	expectedMeasurements := []string{
+		`csi_sidecar_operations_started_total{c=333,driver_name=fake.csi.driver.io,method_name=/csi.v1.Controller/CreateVolume} 1`,
+		`csi_sidecar_operations_in_flight{c=333,driver_name=fake.csi.driver.io,method_name=/csi.v1.Controller/CreateVolume} 1`,
+		`csi_sidecar_operations_in_flight{c=333,driver_name=fake.csi.driver.io,method_name=/csi.v1.Controller/CreateVolume} -1`,
+		`csi_sidecar_operations_seconds{a=111,c=333,driver_name=fake.csi.driver.io,grpc_status_code=Internal,method_name=/csi.v1.Controller/CreateVolume}`,
+		`csi_sidecar_operations_seconds{a=,c=333,driver_name=fake.csi.driver.io,grpc_status_code=OK,method_name=myOperation} 20`,
+	}
+	if got, want := strings.Join(provider.measurements, "\n"), strings.Join(expectedMeasurements, "\n"); got != want {
+		t.Errorf("expected measurements:\n%s\ngot:\n%s", want, got)
+	}
+
+	// The Prometheus metrics are still recorded.
+	vec, err := testutil.GetHistogramVecFromGatherer(cmm.GetRegistry(), SidecarOperationMetric, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := vec.GetAggregatedSampleCount(); count != 2 {
+		t.Errorf("expected 2 Prometheus samples, got %d", count)
+	}
+}
@@ -0,0 +1,225 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// CSI Operations covered by an SLO - Counter Metric
+	sloTotalMetricName = "operations_slo_total"
+	sloTotalHelp       = "Total number of Container Storage Interface operations which are covered by a service level objective"
+
+	// CSI Operations which met their SLO - Counter Metric
+	sloGoodMetricName = "operations_slo_good_total"
+	sloGoodHelp       = "Total number of Container Storage Interface operations which met their service level objective"
+)
+
+// SLO defines a service level objective for a CSI operation, for example
+// "99% of NodePublishVolume calls complete in less than 5s with OK".
+type SLO struct {
+	// Method is the full name of the CSI operation, for example
+	// "/csi.v1.Node/NodePublishVolume".
+	Method string
+	// Threshold is the maximum duration of a good operation. It must be
+	// positive.
+	Threshold time.Duration
+	// GoodCodes are the gRPC status codes of good operations. The
+	// default is codes.OK. Errors which are not gRPC errors count as
+	// codes.Unknown.
+	GoodCodes []codes.Code
+	// Objective is the fraction of good operations, for example 0.99.
+	// It must be between 0 and 1. Only SLORules uses it, but WithSLOs
+	// also rejects SLOs with an invalid objective.
+	Objective float64
+}
+
+// WithSLOs enables the operations_slo_total and operations_slo_good_total
+// counters for the methods of the SLOs. Each completed operation of such a
+// method increments the total counter and, if it met the SLO, also the
+// good counter. Their ratio is the SLO compliance, without having to
+// compute it from the operations_seconds histogram, whose buckets might
+// not match the threshold.
+//
+// There can be at most one SLO per method. Later SLOs replace earlier
+// ones. SLORules generates Prometheus rules for the counters. Invalid SLOs,
+// the same ones that SLORules rejects, are logged as errors and skipped.
+// Without any valid SLO, the counters are disabled.
+func WithSLOs(slos ...SLO) MetricsManagerOption {
+	return func(cmm *csiMetricsManager) {
+		for _, slo := range slos {
+			if err := errors.Join(slo.validate()...); err != nil {
+				klog.Background().Error(err, "Ignoring invalid SLO", "method", slo.Method)
+				continue
+			}
+			if slo.GoodCodes == nil {
+				slo.GoodCodes = []codes.Code{codes.OK}
+			}
+			// The counters only get registered if there is at least one
+			// valid SLO.
+			if cmm.slos == nil {
+				cmm.slos = map[string]SLO{}
+			}
+			cmm.slos[slo.Method] = slo
+		}
+	}
+}
+
+// validate returns all problems of the SLO.
+func (slo SLO) validate() []error {
+	var errs []error
+	if slo.Method == "" {
+		errs = append(errs, errors.New("method is empty"))
+	}
+	if slo.Threshold <= 0 {
+		errs = append(errs, fmt.Errorf("threshold %s must be positive", slo.Threshold))
+	}
+	if slo.Objective <= 0 || slo.Objective >= 1 {
+		errs = append(errs, fmt.Errorf("objective %v must be between 0 and 1", slo.Objective))
+	}
+	return errs
+}
+
+// recordSLO updates the SLO counters if there is an SLO for the operation.
+func (cmm *csiMetricsManager) recordSLO(ctx context.Context, operationName string, operationErr error, operationDuration time.Duration) {
+	slo, ok := cmm.slos[operationName]
+	if !ok {
+		return
+	}
+	values := cmm.fixedLabelValues(operationName)
+	good := operationDuration <= slo.Threshold && slices.Contains(slo.GoodCodes, status.Code(operationErr))
+	cmm.csiSLOTotalMetric.WithLabelValues(values...).Inc()
+	if good {
+		cmm.csiSLOGoodMetric.WithLabelValues(values...).Inc()
+	}
+	if cmm.otel != nil {
+		otelAttributes := attributes(cmm.otel.inFlightLabels, values)
+		cmm.otel.sloTotal.Add(ctx, 1, otelAttributes)
+		if good {
+			cmm.otel.sloGood.Add(ctx, 1, otelAttributes)
+		}
+	}
+}
+
+// Multi-window, multi-burn-rate alerts as recommended in chapter 5 of
+// the Google SRE workbook. A burn rate of 1 uses up the error budget in
+// exactly the SLO period of 30 days.
+var sloBurnRateAlerts = []struct {
+	suffix      string
+	severity    string
+	burnRate    float64
+	longWindow  string
+	shortWindow string
+	forDuration string
+}{
+	// 2% of the budget within one hour.
+	{suffix: "FastBurn", severity: "page", burnRate: 14.4, longWindow: "1h", shortWindow: "5m", forDuration: "2m"},
+	// 5% of the budget within six hours.
+	{suffix: "SlowBurn", severity: "ticket", burnRate: 6, longWindow: "6h", shortWindow: "30m", forDuration: "15m"},
+}
+
+var sloWindows = []string{"5m", "30m", "1h", "6h"}
+
+type ruleFile struct {
+	Groups []ruleGroup `json:"groups"`
+}
+
+type ruleGroup struct {
+	Name  string `json:"name"`
+	Rules []rule `json:"rules"`
+}
+
+type rule struct {
+	Record      string            `json:"record,omitempty"`
+	Alert       string            `json:"alert,omitempty"`
+	Expr        string            `json:"expr"`
+	For         string            `json:"for,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// SLORules generates a Prometheus rule file for the counters enabled by
+// WithSLOs. The subsystem must be the one of the metrics manager, for
+// example SubsystemSidecar.
+//
+// The recording rules compute the ratio of bad operations per driver and
+// method over several windows. For each SLO there are two alerts which
+// fire when the error budget of a 30 day period gets used up too quickly,
+// a fast burn alert with severity "page" and a slow burn alert with
+// severity "ticket". testdata/slo-rules.yaml in this package contains an
+// example.
+func SLORules(subsystem string, slos ...SLO) ([]byte, error) {
+	var errs []error
+	for i, slo := range slos {
+		for _, err := range slo.validate() {
+			errs = append(errs, fmt.Errorf("SLO #%d: %w", i, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	recording := ruleGroup{Name: subsystem + "-slo-recording"}
+	for _, window := range sloWindows {
+		recording.Rules = append(recording.Rules, rule{
+			Record: sloErrorRatioName(subsystem, window),
+			Expr: fmt.Sprintf("1 - (sum by (driver_name, method_name) (rate(%[1]s_%[2]s[%[4]s])) / sum by (driver_name, method_name) (rate(%[1]s_%[3]s[%[4]s])))",
+				subsystem, sloGoodMetricName, sloTotalMetricName, window),
+		})
+	}
+	alerting := ruleGroup{Name: subsystem + "-slo-alerts"}
+	for _, slo := range slos {
+		budget := 1 - slo.Objective
+		for _, alert := range sloBurnRateAlerts {
+			threshold := strconv.FormatFloat(alert.burnRate*budget, 'g', 6, 64)
+			selector := fmt.Sprintf("{method_name=%q}", slo.Method)
+			alerting.Rules = append(alerting.Rules, rule{
+				Alert: "CSIOperationSLO" + alert.suffix,
+				Expr: fmt.Sprintf("%s%s > %s and %s%s > %s",
+					sloErrorRatioName(subsystem, alert.longWindow), selector, threshold,
+					sloErrorRatioName(subsystem, alert.shortWindow), selector, threshold),
+				For: alert.forDuration,
+				Labels: map[string]string{
+					"severity": alert.severity,
+				},
+				Annotations: map[string]string{
+					"summary": fmt.Sprintf("%s of {{ $labels.driver_name }} burns the error budget of its %s SLO too fast", slo.Method, strconv.FormatFloat(slo.Objective*100, 'g', 6, 64)+"%"),
+					"description": fmt.Sprintf("More than %s of the %s calls failed or took longer than %s in the last %s.",
+						strconv.FormatFloat(alert.burnRate*budget*100, 'g', 6, 64)+"%", slo.Method, slo.Threshold, alert.longWindow),
+				},
+			})
+		}
+	}
+	return yaml.Marshal(ruleFile{Groups: []ruleGroup{recording, alerting}})
+}
+
+// sloErrorRatioName follows the level:metric:operations naming
+// convention for recording rules.
+func sloErrorRatioName(subsystem, window string) string {
+	return fmt.Sprintf("driver_name_method_name:%s_operations_slo_errors:ratio_rate%s", subsystem, window)
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files in testdata")
+
+var exampleSLOs = []SLO{
+	{
+		Method:    "/csi.v1.Node/NodePublishVolume",
+		Threshold: 5 * time.Second,
+		Objective: 0.99,
+	},
+	{
+		Method:    "/csi.v1.Controller/CreateVolume",
+		Threshold: 2 * time.Minute,
+		GoodCodes: []codes.Code{codes.OK, codes.AlreadyExists},
+		Objective: 0.999,
+	},
+}
+
+func TestSLOs(t *testing.T) {
+	// Arrange
+	cmm := NewCSIMetricsManagerWithOptions(
+		"fake.csi.driver.io", /* driverName */
+		WithSLOs(exampleSLOs...),
+	)
+
+	// Act
+	for _, operation := range []struct {
+		method   string
+		err      error
+		duration time.Duration
+	}{
+		{"/csi.v1.Node/NodePublishVolume", nil, time.Second},
+		{"/csi.v1.Node/NodePublishVolume", nil, 5 * time.Second},
+		{"/csi.v1.Node/NodePublishVolume", nil, 6 * time.Second},
+		{"/csi.v1.Node/NodePublishVolume", status.Error(codes.Internal, "fake error"), time.Second},
+		{"/csi.v1.Controller/CreateVolume", status.Error(codes.AlreadyExists, "exists"), time.Second},
+		{"/csi.v1.Controller/CreateVolume", errors.New("not a gRPC error"), time.Second},
+		{"/csi.v1.Node/NodeUnpublishVolume", nil, time.Second},
+	} {
+		cmm.RecordMetrics(operation.method, operation.err, operation.duration)
+	}
+
+	// Assert
+	expectedMetrics := `# HELP csi_sidecar_operations_slo_good_total [ALPHA] Total number of Container Storage Interface operations which met their service level objective
+		# TYPE csi_sidecar_operations_slo_good_total counter
+		csi_sidecar_operations_slo_good_total{driver_name="fake.csi.driver.io",method_name="/csi.v1.Controller/CreateVolume"} 1
+		csi_sidecar_operations_slo_good_total{driver_name="fake.csi.driver.io",method_name="/csi.v1.Node/NodePublishVolume"} 2
+		# HELP csi_sidecar_operations_slo_total [ALPHA] Total number of Container Storage Interface operations which are covered by a service level objective
+		# TYPE csi_sidecar_operations_slo_total counter
+		csi_sidecar_operations_slo_total{driver_name="fake.csi.driver.io",method_name="/csi.v1.Controller/CreateVolume"} 2
+		csi_sidecar_operations_slo_total{driver_name="fake.csi.driver.io",method_name="/csi.v1.Node/NodePublishVolume"} 4
+	`
+	if err := testutil.GatherAndCompare(
+		cmm.GetRegistry(), strings.NewReader(expectedMetrics), "csi_sidecar_operations_slo_total", "csi_sidecar_operations_slo_good_total"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSLORules(t *testing.T) {
+	rules, err := SLORules(SubsystemSidecar, exampleSLOs...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	golden := "testdata/slo-rules.yaml"
+	if *updateGolden {
+		if err := os.WriteFile(golden, rules, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rules) != string(expected) {
+		t.Errorf("rules differ from %s, run \"go test ./metrics -run TestSLORules -update\" to update it:\n%s", golden, rules)
+	}
+
+	_, err = SLORules(SubsystemSidecar, SLO{Objective: 1})
+	expectedErr := "SLO #0: method is empty\nSLO #0: threshold 0s must be positive\nSLO #0: objective 1 must be between 0 and 1"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("expected error %q, got %v", expectedErr, err)
+	}
+}
+
+func TestInvalidSLOs(t *testing.T) {
+	// Arrange
+	cmm := NewCSIMetricsManagerWithOptions(
+		"fake.csi.driver.io", /* driverName */
+		WithSLOs(
+			SLO{Threshold: time.Second, Objective: 0.99},
+			SLO{Method: "/csi.v1.Node/NodeStageVolume", Objective: 0.99},
+			SLO{Method: "/csi.v1.Node/NodeUnstageVolume", Threshold: time.Second, Objective: 1},
+			SLO{Method: "/csi.v1.Node/NodeExpandVolume", Threshold: time.Second},
+			exampleSLOs[0],
+		),
+	)
+
+	// Act
+	for _, method := range []string{
+		"",
+		"/csi.v1.Node/NodeStageVolume",
+		"/csi.v1.Node/NodeUnstageVolume",
+		"/csi.v1.Node/NodeExpandVolume",
+		"/csi.v1.Node/NodePublishVolume",
+	} {
+		cmm.RecordMetrics(method, nil, time.Millisecond)
+	}
+
+	// Assert
+	expectedMetrics := `# HELP csi_sidecar_operations_slo_total [ALPHA] Total number of Container Storage Interface operations which are covered by a service level objective
+		# TYPE csi_sidecar_operations_slo_total counter
+		csi_sidecar_operations_slo_total{driver_name="fake.csi.driver.io",method_name="/csi.v1.Node/NodePublishVolume"} 1
+	`
+	if err := testutil.GatherAndCompare(
+		cmm.GetRegistry(), strings.NewReader(expectedMetrics), "csi_sidecar_operations_slo_total"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNoValidSLOs(t *testing.T) {
+	for name, option := range map[string]MetricsManagerOption{
+		"none":    WithSLOs(),
+		"invalid": WithSLOs(SLO{Method: "/csi.v1.Node/NodeStageVolume"}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			cmm := NewCSIMetricsManagerWithOptions(
+				"fake.csi.driver.io", /* driverName */
+				option,
+			)
+			if cmm.(*csiMetricsManager).csiSLOTotalMetric != nil {
+				t.Error("SLO counters registered without a valid SLO")
+			}
+			cmm.RecordMetrics("/csi.v1.Node/NodeStageVolume", nil, time.Millisecond)
+		})
+	}
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protosanitizer
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// AuditReport is the result of AuditMessage. It can be serialized
+// with encoding/json for machine-readable output.
+type AuditReport struct {
+	// Stripped lists all fields which StripSecrets replaces with
+	// "***stripped***".
+	Stripped []FieldFinding `json:"stripped"`
+
+	// Suspicious lists all fields which are logged by StripSecrets
+	// although their name looks like they contain secrets. Either
+	// the field needs to be annotated as secret or, if it is harmless,
+	// the finding can be ignored by the caller.
+	Suspicious []FieldFinding `json:"suspicious"`
+}
+
+// FieldFinding identifies one field in a message.
+type FieldFinding struct {
+	// Path is the location of the field inside the audited message,
+	// using the same names as the output of StripSecrets. List
+	// entries and map values are identified by index or key in
+	// brackets, for example "volume_capabilities[0].mount.fs_type".
+	Path string `json:"path"`
+
+	// Field is the fully-qualified name of the field in the proto schema.
+	Field protoreflect.FullName `json:"field"`
+}
+
+// String returns a human-readable, multi-line summary of the report.
+func (r AuditReport) String() string {
+	var b strings.Builder
+	for _, f := range r.Stripped {
+		fmt.Fprintf(&b, "stripped: %s (%s)\n", f.Path, f.Field)
+	}
+	for _, f := range r.Suspicious {
+		fmt.Fprintf(&b, "suspicious: %s (%s)\n", f.Path, f.Field)
+	}
+	return b.String()
+}
+
+// AuditMessage walks through all fields that are set in the message and
+// reports which of them get stripped by StripSecrets and which of them
+// have a name that looks like a secret without being annotated as one.
+// It is meant to be used in unit tests of CSI drivers to ensure that
+// no credentials are leaked through fields of vendor extensions which
+// lack the secret annotation.
+//
+// The name check is a heuristic. It looks for words like "secret",
+// "password" or "credentials" and for combinations like "private_key"
+// or "access_token" in the field name.
+//
+// The findings are sorted by path. The lists are empty, not nil, when
+// there are no findings.
+func AuditMessage(msg proto.Message) AuditReport {
+	report := AuditReport{
+		Stripped:   []FieldFinding{},
+		Suspicious: []FieldFinding{},
+	}
+	if msg != nil {
+		auditMessage(&report, "", msg.ProtoReflect())
+	}
+	// Range visits fields and map entries in random order.
+	byPath := func(a, b FieldFinding) int { return cmp.Compare(a.Path, b.Path) }
+	slices.SortFunc(report.Stripped, byPath)
+	slices.SortFunc(report.Suspicious, byPath)
+	return report
+}
+
+func auditMessage(report *AuditReport, prefix string, msg protoreflect.Message) {
+	msg.Range(func(field protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		path := field.TextName()
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+		finding := FieldFinding{Path: path, Field: field.FullName()}
+		if isSecret(field) {
+			report.Stripped = append(report.Stripped, finding)
+			// Nothing inside the field gets logged, so there is no
+			// need to look further.
+			return true
+		}
+		if looksLikeSecret(field.TextName()) {
+			report.Suspicious = append(report.Suspicious, finding)
+		}
+		auditValue(report, path, field, v)
+		return true
+	})
+}
+
+func auditValue(report *AuditReport, path string, field protoreflect.FieldDescriptor, v protoreflect.Value) {
+	switch {
+	case field.IsList():
+		if field.Kind() != protoreflect.MessageKind {
+			return
+		}
+		l := v.List()
+		for i := range l.Len() {
+			auditMessage(report, fmt.Sprintf("%s[%d]", path, i), l.Get(i).Message())
+		}
+	case field.IsMap():
+		if field.MapValue().Kind() != protoreflect.MessageKind {
+			return
+		}
+		v.Map().Range(func(mk protoreflect.MapKey, v protoreflect.Value) bool {
+			auditMessage(report, fmt.Sprintf("%s[%s]", path, mk.String()), v.Message())
+			return true
+		})
+	case field.Kind() == protoreflect.MessageKind:
+		auditMessage(report, path, v.Message())
+	}
+}
+
+var (
+	// secretWords are words which, when used anywhere in a field name,
+	// indicate that the field holds a secret.
+	secretWords = map[string]bool{
+		"secret":      true,
+		"secrets":     true,
+		"password":    true,
+		"passwords":   true,
+		"passwd":      true,
+		"passphrase":  true,
+		"credential":  true,
+		"credentials": true,
+		"apikey":      true,
+		"privatekey":  true,
+	}
+
+	// secretWordPairs are combinations of two consecutive words which
+	// indicate a secret. "token" and "key" alone are too common
+	// (starting_token, topology keys) to be used on their own.
+	secretWordPairs = map[[2]string]bool{
+		{"private", "key"}:    true,
+		{"access", "key"}:     true,
+		{"api", "key"}:        true,
+		{"encryption", "key"}: true,
+		{"access", "token"}:   true,
+		{"auth", "token"}:     true,
+		{"bearer", "token"}:   true,
+		{"refresh", "token"}:  true,
+		{"session", "token"}:  true,
+	}
+)
+
+// looksLikeSecret checks whether a snake_case field name contains
+// one of the secretWords or secretWordPairs.
+func looksLikeSecret(name string) bool {
+	words := strings.Split(strings.ToLower(name), "_")
+	for i, word := range words {
+		if secretWords[word] {
+			return true
+		}
+		if i > 0 && secretWordPairs[[2]string{words[i-1], word}] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protosanitizer
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubernetes-csi/csi-lib-utils/protosanitizer/test/csitest"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestAuditMessage(t *testing.T) {
+	cases := map[string]struct {
+		msg      proto.Message
+		expected AuditReport
+	}{
+		"nil": {
+			expected: AuditReport{
+				Stripped:   []FieldFinding{},
+				Suspicious: []FieldFinding{},
+			},
+		},
+		"empty": {
+			msg: &csi.CreateVolumeRequest{},
+			expected: AuditReport{
+				Stripped:   []FieldFinding{},
+				Suspicious: []FieldFinding{},
+			},
+		},
+		"current spec": {
+			msg: &testReq,
+			expected: AuditReport{
+				Stripped: []FieldFinding{
+					{Path: "secrets", Field: "csi.v1.CreateVolumeRequest.secrets"},
+				},
+				Suspicious: []FieldFinding{},
+			},
+		},
+		"revised spec": {
+			msg: &csitest.CreateVolumeRequest{
+				Name: "foo",
+				MaybeSecretMap: map[int64]*csitest.VolumeCapability{
+					1: {ArraySecret: "aaa"},
+				},
+				Seecreets: map[string]string{"a": "b"},
+				VolumeCapabilities: []*csitest.VolumeCapability{
+					{
+						AccessType: &csitest.VolumeCapability_Mount{
+							Mount: &csitest.VolumeCapability_MountVolume{
+								FsType: "ext4",
+							},
+						},
+					},
+					{
+						ArraySecret: "Who's there?",
+					},
+				},
+				VolumeContentSource: &csitest.VolumeContentSource{
+					Type: &csitest.VolumeContentSource_Volume{
+						Volume: &csitest.VolumeContentSource_VolumeSource{
+							VolumeId:         "abc",
+							OneofSecretField: "hello",
+						},
+					},
+				},
+			},
+			expected: AuditReport{
+				Stripped: []FieldFinding{
+					{Path: "maybe_secret_map[1].array_secret", Field: "csitest.v1.VolumeCapability.array_secret"},
+					{Path: "seecreets", Field: "csitest.v1.CreateVolumeRequest.seecreets"},
+					{Path: "volume_capabilities[1].array_secret", Field: "csitest.v1.VolumeCapability.array_secret"},
+					{Path: "volume_content_source.volume.oneof_secret_field", Field: "csitest.v1.VolumeContentSource.VolumeSource.oneof_secret_field"},
+				},
+				Suspicious: []FieldFinding{
+					{Path: "maybe_secret_map", Field: "csitest.v1.CreateVolumeRequest.maybe_secret_map"},
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			report := AuditMessage(tc.msg)
+			assert.Equal(t, tc.expected, report)
+		})
+	}
+}
+
+func TestAuditReportJSON(t *testing.T) {
+	report := AuditMessage(&testReq)
+	data, err := json.Marshal(report)
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `{"stripped":[{"path":"secrets","field":"csi.v1.CreateVolumeRequest.secrets"}],"suspicious":[]}`, string(data))
+	}
+	assert.Equal(t, "stripped: secrets (csi.v1.CreateVolumeRequest.secrets)\n", report.String())
+}
+
+func TestLooksLikeSecret(t *testing.T) {
+	for name, expected := range map[string]bool{
+		"secrets":            true,
+		"node_stage_secrets": true,
+		"db_password":        true,
+		"aws_access_key":     true,
+		"AccessToken":        false,
+		"access_token":       true,
+		"starting_token":     false,
+		"next_token":         false,
+		"volume_id":          false,
+		"key":                false,
+	} {
+		assert.Equal(t, expected, looksLikeSecret(name), name)
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protosanitizer
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// Policy determines which fields of a message get serialized.
+// Secret fields are always replaced with "***stripped***".
+//
+// The zero value is the policy used by StripSecrets: all fields
+// are included.
+type Policy struct {
+	allowed allowlist
+}
+
+// NewAllowlistPolicy returns a policy which only includes the fields
+// listed in the field mask, for example:
+//
+//	&fieldmaskpb.FieldMask{Paths: []string{"volume_id", "capacity_range", "volume_capabilities.access_mode"}}
+//
+// A path selects a field by its proto name. Nested fields are separated
+// by dots. Selecting a field includes everything inside it. Lists and
+// maps of messages are transparent, i.e. "volume_capabilities.access_mode"
+// selects the access mode in each entry of the volume_capabilities list.
+//
+// The same policy gets applied to all messages, regardless of their type.
+// Fields not present in a message are ignored. An empty field mask
+// leads to empty messages.
+func NewAllowlistPolicy(mask *fieldmaskpb.FieldMask) (Policy, error) {
+	allowed := allowlist{}
+	for _, path := range mask.GetPaths() {
+		if err := allowed.add(path); err != nil {
+			return Policy{}, err
+		}
+	}
+	return Policy{allowed: allowed}, nil
+}
+
+// StripSecrets behaves like the StripSecrets function, except that fields
+// which are not allowed by the policy are left out.
+func (p Policy) StripSecrets(msg interface{}) fmt.Stringer {
+	return &stripSecrets{msg: msg, allowed: p.allowed}
+}
+
+// allowlist is a tree of field names. A nil allowlist as value
+// means that the field and everything inside it is allowed.
+type allowlist map[string]allowlist
+
+func (a allowlist) add(path string) error {
+	names := strings.Split(path, ".")
+	for _, name := range names {
+		if name == "" {
+			return fmt.Errorf("invalid field path %q: empty field name", path)
+		}
+	}
+
+	node := a
+	for i, name := range names {
+		child, ok := node[name]
+		switch {
+		case ok && child == nil:
+			// Already allowed completely, a more specific path
+			// does not change that.
+			return nil
+		case i == len(names)-1:
+			node[name] = nil
+			return nil
+		case !ok:
+			child = allowlist{}
+			node[name] = child
+		}
+		node = child
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protosanitizer
+
+import (
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+func TestAllowlistPolicy(t *testing.T) {
+	cases := map[string]struct {
+		paths       []string
+		msg         interface{}
+		expected    string
+		expectedErr bool
+	}{
+		"default": {
+			paths:    nil,
+			msg:      &testReq,
+			expected: `{}`,
+		},
+		"scalar": {
+			paths:    []string{"name"},
+			msg:      "hello world",
+			expected: `"hello world"`,
+		},
+		"top-level": {
+			paths:    []string{"name", "capacity_range", "volume_capabilities"},
+			msg:      &testReq,
+			expected: `{"capacity_range":{"limit_bytes":1024,"required_bytes":1024},"name":"test-volume","volume_capabilities":[{"access_mode":{"mode":"MULTI_NODE_MULTI_WRITER"},"mount":{"fs_type":"ext4","mount_flags":["flag1","flag2","flag3"]}}]}`,
+		},
+		"nested": {
+			paths:    []string{"capacity_range.required_bytes", "volume_capabilities.access_mode"},
+			msg:      &testReq,
+			expected: `{"capacity_range":{"required_bytes":1024},"volume_capabilities":[{"access_mode":{"mode":"MULTI_NODE_MULTI_WRITER"}}]}`,
+		},
+		"nested and parent": {
+			paths:    []string{"capacity_range.required_bytes", "capacity_range"},
+			msg:      &testReq,
+			expected: `{"capacity_range":{"limit_bytes":1024,"required_bytes":1024}}`,
+		},
+		"secrets": {
+			paths:    []string{"secrets"},
+			msg:      &testReq,
+			expected: `{"secrets":"***stripped***"}`,
+		},
+		"other message": {
+			paths: []string{"volume"},
+			msg: &csi.CreateVolumeResponse{
+				Volume: &csi.Volume{
+					VolumeId: "foo",
+				},
+			},
+			expected: `{"volume":{"volume_id":"foo"}}`,
+		},
+		"invalid": {
+			paths:       []string{"capacity_range..required_bytes"},
+			expectedErr: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			policy, err := NewAllowlistPolicy(&fieldmaskpb.FieldMask{Paths: tc.paths})
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.expected, policy.StripSecrets(tc.msg).String())
+			}
+		})
+	}
+}
+
+func TestDefaultPolicy(t *testing.T) {
+	var policy Policy
+	assert.Equal(t, StripSecrets(&testReq).String(), policy.StripSecrets(&testReq).String())
+}
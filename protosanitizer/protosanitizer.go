@@ -21,12 +21,68 @@ package protosanitizer
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
 )
 
+var (
+	// secretExtensions holds the additional markers registered with
+	// RegisterSecretExtensions. It gets replaced as a whole under
+	// secretExtensionsMutex, so readers don't need to lock.
+	secretExtensions      atomic.Pointer[[]protoreflect.ExtensionType]
+	secretExtensionsMutex sync.Mutex
+)
+
+// RegisterSecretExtensions adds field options which mark a field as secret,
+// in addition to the csi.E_CsiSecret extension from the CSI spec. This is
+// meant for vendors who define their own proto services next to CSI (for
+// example, snapshot metadata or replication extensions) and annotate secret
+// fields with their own extension.
+//
+// Each extension must extend google.protobuf.FieldOptions and be a
+// singular bool, like csi_secret. Registering the same extension more than
+// once is a no-op. The registry is global and applies to all messages
+// passed to StripSecrets, therefore it is usually populated during
+// program initialization.
+func RegisterSecretExtensions(exts ...protoreflect.ExtensionType) error {
+	fieldOptions := (&descriptorpb.FieldOptions{}).ProtoReflect().Descriptor().FullName()
+	for _, ext := range exts {
+		if ext == nil {
+			return fmt.Errorf("secret extension must not be nil")
+		}
+		desc := ext.TypeDescriptor()
+		if extendee := desc.ContainingMessage().FullName(); extendee != fieldOptions {
+			return fmt.Errorf("secret extension %s extends %s instead of %s", desc.FullName(), extendee, fieldOptions)
+		}
+		if desc.Kind() != protoreflect.BoolKind || desc.Cardinality() == protoreflect.Repeated {
+			return fmt.Errorf("secret extension %s must be a singular bool", desc.FullName())
+		}
+	}
+
+	secretExtensionsMutex.Lock()
+	defer secretExtensionsMutex.Unlock()
+	var registered []protoreflect.ExtensionType
+	if old := secretExtensions.Load(); old != nil {
+		registered = slices.Clone(*old)
+	}
+	for _, ext := range exts {
+		name := ext.TypeDescriptor().FullName()
+		if !slices.ContainsFunc(registered, func(e protoreflect.ExtensionType) bool {
+			return e.TypeDescriptor().FullName() == name
+		}) {
+			registered = append(registered, ext)
+		}
+	}
+	secretExtensions.Store(&registered)
+	return nil
+}
+
 // StripSecrets returns a wrapper around the original CSI gRPC message
 // which has a Stringer implementation that serializes the message
 // as one-line JSON, but without including secret information.
@@ -34,17 +90,22 @@ import (
 // included in the result.
 //
 // StripSecrets relies on an extension in CSI 1.0 and thus can only
-// be used for messages based on that or a more recent spec!
+// be used for messages based on that or a more recent spec! Fields
+// marked with an extension registered via RegisterSecretExtensions
+// are stripped as well.
 //
 // StripSecrets itself is fast and therefore it is cheap to pass the
 // result to logging functions which may or may not end up serializing
 // the parameter depending on the current log level.
 func StripSecrets(msg interface{}) fmt.Stringer {
-	return &stripSecrets{msg}
+	return &stripSecrets{msg: msg}
 }
 
 type stripSecrets struct {
 	msg any
+
+	// allowed is nil if all fields are to be included.
+	allowed allowlist
 }
 
 func (s *stripSecrets) String() string {
@@ -53,7 +114,7 @@ func (s *stripSecrets) String() string {
 	// also support scalar types like string, int, etc.
 	msg, ok := s.msg.(proto.Message)
 	if ok {
-		stripped = stripMessage(msg.ProtoReflect())
+		stripped = stripMessage(msg.ProtoReflect(), s.allowed)
 	}
 
 	b, err := json.Marshal(stripped)
@@ -63,10 +124,10 @@ func (s *stripSecrets) String() string {
 	return string(b)
 }
 
-func stripSingleValue(field protoreflect.FieldDescriptor, v protoreflect.Value) any {
+func stripSingleValue(field protoreflect.FieldDescriptor, v protoreflect.Value, allowed allowlist) any {
 	switch field.Kind() {
 	case protoreflect.MessageKind:
-		return stripMessage(v.Message())
+		return stripMessage(v.Message(), allowed)
 	case protoreflect.EnumKind:
 		desc := field.Enum().Values().ByNumber(v.Enum())
 		if desc == nil {
@@ -78,44 +139,69 @@ func stripSingleValue(field protoreflect.FieldDescriptor, v protoreflect.Value)
 	}
 }
 
-func stripValue(field protoreflect.FieldDescriptor, v protoreflect.Value) any {
+func stripValue(field protoreflect.FieldDescriptor, v protoreflect.Value, allowed allowlist) any {
 	if field.IsList() {
 		l := v.List()
 		res := make([]any, l.Len())
 		for i := range l.Len() {
-			res[i] = stripSingleValue(field, l.Get(i))
+			res[i] = stripSingleValue(field, l.Get(i), allowed)
 		}
 		return res
 	} else if field.IsMap() {
 		m := v.Map()
 		res := make(map[string]any, m.Len())
 		m.Range(func(mk protoreflect.MapKey, v protoreflect.Value) bool {
-			res[mk.String()] = stripSingleValue(field.MapValue(), v)
+			res[mk.String()] = stripSingleValue(field.MapValue(), v, allowed)
 			return true
 		})
 		return res
 	} else {
-		return stripSingleValue(field, v)
+		return stripSingleValue(field, v, allowed)
 	}
 }
 
-func stripMessage(msg protoreflect.Message) map[string]any {
+func stripMessage(msg protoreflect.Message, allowed allowlist) map[string]any {
 	stripped := make(map[string]any)
 
 	// Walk through all fields and replace those with ***stripped*** that
-	// are marked as secret.
+	// are marked as secret. Fields which are not in the allowlist
+	// are skipped entirely.
 	msg.Range(func(field protoreflect.FieldDescriptor, v protoreflect.Value) bool {
 		name := field.TextName()
-		if isCSI1Secret(field) {
+		var fieldAllowed allowlist
+		if allowed != nil {
+			var ok bool
+			fieldAllowed, ok = allowed[name]
+			if !ok {
+				return true
+			}
+		}
+		if isSecret(field) {
 			stripped[name] = "***stripped***"
 		} else {
-			stripped[name] = stripValue(field, v)
+			stripped[name] = stripValue(field, v, fieldAllowed)
 		}
 		return true
 	})
 	return stripped
 }
 
+// isSecret checks the CSI 1.0 extension and all additional extensions
+// registered with RegisterSecretExtensions.
+func isSecret(desc protoreflect.FieldDescriptor) bool {
+	if isCSI1Secret(desc) {
+		return true
+	}
+	if exts := secretExtensions.Load(); exts != nil {
+		for _, ext := range *exts {
+			if ex, ok := proto.GetExtension(desc.Options(), ext).(bool); ok && ex {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // isCSI1Secret uses the csi.E_CsiSecret extension from CSI 1.0 to
 // determine whether a field contains secrets.
 func isCSI1Secret(desc protoreflect.FieldDescriptor) bool {
@@ -186,6 +186,29 @@ func TestStripSecrets(t *testing.T) {
 	assert.NotContains(t, dump, secretValue)
 }
 
+func TestRegisterSecretExtensions(t *testing.T) {
+	old := secretExtensions.Load()
+	defer secretExtensions.Store(old)
+
+	// csi.E_AlphaMessage extends MessageOptions and thus cannot mark fields.
+	assert.Error(t, RegisterSecretExtensions(csi.E_AlphaMessage), "message extension")
+	assert.Error(t, RegisterSecretExtensions(nil), "nil extension")
+
+	req := &csi.CreateVolumeRequest{
+		Name:              "foo",
+		Secrets:           map[string]string{"secret": "123"},
+		MutableParameters: map[string]string{"iops": "1000"},
+	}
+	assert.Equal(t, `{"mutable_parameters":{"iops":"1000"},"name":"foo","secrets":"***stripped***"}`, StripSecrets(req).String(), "before registration")
+
+	// csi.E_AlphaField stands in for a vendor-specific marker here.
+	if assert.NoError(t, RegisterSecretExtensions(csi.E_AlphaField)) &&
+		assert.NoError(t, RegisterSecretExtensions(csi.E_AlphaField), "duplicate registration") {
+		assert.Len(t, *secretExtensions.Load(), 1)
+		assert.Equal(t, `{"mutable_parameters":"***stripped***","name":"foo","secrets":"***stripped***"}`, StripSecrets(req).String(), "after registration")
+	}
+}
+
 func BenchmarkStrip(b *testing.B) {
 	msg := StripSecrets(&testReq)
 	for i := 0; i < b.N; i++ {
@@ -0,0 +1,257 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rpc
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Capabilities combines the capabilities of the different CSI services.
+type Capabilities struct {
+	Plugin          PluginCapabilitySet
+	Controller      ControllerCapabilitySet
+	GroupController GroupControllerCapabilitySet
+}
+
+// GetCapabilities returns the plugin capabilities and, if the corresponding
+// services are advertised, the controller and group controller capabilities.
+// The sets for services that are not advertised are empty.
+func GetCapabilities(ctx context.Context, conn *grpc.ClientConn) (Capabilities, error) {
+	caps := Capabilities{
+		Controller:      ControllerCapabilitySet{},
+		GroupController: GroupControllerCapabilitySet{},
+	}
+	var err error
+	caps.Plugin, err = GetPluginCapabilities(ctx, conn)
+	if err != nil {
+		return Capabilities{}, fmt.Errorf("get plugin capabilities: %w", err)
+	}
+	if caps.Plugin[csi.PluginCapability_Service_CONTROLLER_SERVICE] {
+		caps.Controller, err = GetControllerCapabilities(ctx, conn)
+		if err != nil {
+			return Capabilities{}, fmt.Errorf("get controller capabilities: %w", err)
+		}
+	}
+	if caps.Plugin[csi.PluginCapability_Service_GROUP_CONTROLLER_SERVICE] {
+		caps.GroupController, err = GetGroupControllerCapabilities(ctx, conn)
+		if err != nil {
+			return Capabilities{}, fmt.Errorf("get group controller capabilities: %w", err)
+		}
+	}
+	return caps, nil
+}
+
+// CapabilityDiff lists the capabilities that were added or removed.
+// All slices are sorted.
+type CapabilityDiff struct {
+	PluginAdded            []csi.PluginCapability_Service_Type
+	PluginRemoved          []csi.PluginCapability_Service_Type
+	ControllerAdded        []csi.ControllerServiceCapability_RPC_Type
+	ControllerRemoved      []csi.ControllerServiceCapability_RPC_Type
+	GroupControllerAdded   []csi.GroupControllerServiceCapability_RPC_Type
+	GroupControllerRemoved []csi.GroupControllerServiceCapability_RPC_Type
+}
+
+// Empty returns true if nothing changed.
+func (d CapabilityDiff) Empty() bool {
+	return len(d.PluginAdded) == 0 && len(d.PluginRemoved) == 0 &&
+		len(d.ControllerAdded) == 0 && len(d.ControllerRemoved) == 0 &&
+		len(d.GroupControllerAdded) == 0 && len(d.GroupControllerRemoved) == 0
+}
+
+// DiffCapabilities compares two sets of capabilities.
+func DiffCapabilities(oldCaps, newCaps Capabilities) CapabilityDiff {
+	var d CapabilityDiff
+	d.PluginAdded, d.PluginRemoved = diffSet(oldCaps.Plugin, newCaps.Plugin)
+	d.ControllerAdded, d.ControllerRemoved = diffSet(oldCaps.Controller, newCaps.Controller)
+	d.GroupControllerAdded, d.GroupControllerRemoved = diffSet(oldCaps.GroupController, newCaps.GroupController)
+	return d
+}
+
+func diffSet[K cmp.Ordered](oldSet, newSet map[K]bool) (added, removed []K) {
+	for k, v := range newSet {
+		if v && !oldSet[k] {
+			added = append(added, k)
+		}
+	}
+	for k, v := range oldSet {
+		if v && !newSet[k] {
+			removed = append(removed, k)
+		}
+	}
+	slices.Sort(added)
+	slices.Sort(removed)
+	return added, removed
+}
+
+// capabilityType is implemented by the enums used as keys in the capability sets.
+type capabilityType interface {
+	~int32
+	Descriptor() protoreflect.EnumDescriptor
+}
+
+// List returns the names of all capabilities in the set, sorted alphabetically.
+func (s PluginCapabilitySet) List() []string { return capabilityList(s) }
+
+// String returns the sorted, comma-separated names of all capabilities in the set.
+func (s PluginCapabilitySet) String() string { return strings.Join(s.List(), ",") }
+
+// MarshalJSON encodes the set as sorted list of capability names.
+func (s PluginCapabilitySet) MarshalJSON() ([]byte, error) { return json.Marshal(s.List()) }
+
+// UnmarshalJSON decodes a list of capability names.
+func (s *PluginCapabilitySet) UnmarshalJSON(data []byte) error {
+	return unmarshalCapabilities(data, (*map[csi.PluginCapability_Service_Type]bool)(s))
+}
+
+// ParsePluginCapabilitySet parses the output of PluginCapabilitySet.String.
+func ParsePluginCapabilitySet(str string) (PluginCapabilitySet, error) {
+	return parseCapabilities[csi.PluginCapability_Service_Type](str)
+}
+
+// List returns the names of all capabilities in the set, sorted alphabetically.
+func (s ControllerCapabilitySet) List() []string { return capabilityList(s) }
+
+// String returns the sorted, comma-separated names of all capabilities in the set.
+func (s ControllerCapabilitySet) String() string { return strings.Join(s.List(), ",") }
+
+// MarshalJSON encodes the set as sorted list of capability names.
+func (s ControllerCapabilitySet) MarshalJSON() ([]byte, error) { return json.Marshal(s.List()) }
+
+// UnmarshalJSON decodes a list of capability names.
+func (s *ControllerCapabilitySet) UnmarshalJSON(data []byte) error {
+	return unmarshalCapabilities(data, (*map[csi.ControllerServiceCapability_RPC_Type]bool)(s))
+}
+
+// ParseControllerCapabilitySet parses the output of ControllerCapabilitySet.String.
+func ParseControllerCapabilitySet(str string) (ControllerCapabilitySet, error) {
+	return parseCapabilities[csi.ControllerServiceCapability_RPC_Type](str)
+}
+
+// List returns the names of all capabilities in the set, sorted alphabetically.
+func (s GroupControllerCapabilitySet) List() []string { return capabilityList(s) }
+
+// String returns the sorted, comma-separated names of all capabilities in the set.
+func (s GroupControllerCapabilitySet) String() string { return strings.Join(s.List(), ",") }
+
+// MarshalJSON encodes the set as sorted list of capability names.
+func (s GroupControllerCapabilitySet) MarshalJSON() ([]byte, error) { return json.Marshal(s.List()) }
+
+// UnmarshalJSON decodes a list of capability names.
+func (s *GroupControllerCapabilitySet) UnmarshalJSON(data []byte) error {
+	return unmarshalCapabilities(data, (*map[csi.GroupControllerServiceCapability_RPC_Type]bool)(s))
+}
+
+// ParseGroupControllerCapabilitySet parses the output of GroupControllerCapabilitySet.String.
+func ParseGroupControllerCapabilitySet(str string) (GroupControllerCapabilitySet, error) {
+	return parseCapabilities[csi.GroupControllerServiceCapability_RPC_Type](str)
+}
+
+// List returns the names of all capabilities in the set, sorted alphabetically.
+func (s NodeCapabilitySet) List() []string { return capabilityList(s) }
+
+// String returns the sorted, comma-separated names of all capabilities in the set.
+func (s NodeCapabilitySet) String() string { return strings.Join(s.List(), ",") }
+
+// MarshalJSON encodes the set as sorted list of capability names.
+func (s NodeCapabilitySet) MarshalJSON() ([]byte, error) { return json.Marshal(s.List()) }
+
+// UnmarshalJSON decodes a list of capability names.
+func (s *NodeCapabilitySet) UnmarshalJSON(data []byte) error {
+	return unmarshalCapabilities(data, (*map[csi.NodeServiceCapability_RPC_Type]bool)(s))
+}
+
+// ParseNodeCapabilitySet parses the output of NodeCapabilitySet.String.
+func ParseNodeCapabilitySet(str string) (NodeCapabilitySet, error) {
+	return parseCapabilities[csi.NodeServiceCapability_RPC_Type](str)
+}
+
+// capabilityList returns the names of the enabled capabilities. Values
+// which are not defined in the CSI spec used by this package are
+// represented by their number.
+func capabilityList[E capabilityType](set map[E]bool) []string {
+	list := make([]string, 0, len(set))
+	for capability, enabled := range set {
+		if !enabled {
+			continue
+		}
+		if desc := capability.Descriptor().Values().ByNumber(protoreflect.EnumNumber(capability)); desc != nil {
+			list = append(list, string(desc.Name()))
+		} else {
+			list = append(list, strconv.Itoa(int(capability)))
+		}
+	}
+	slices.Sort(list)
+	return list
+}
+
+// parseCapabilities accepts a comma-separated list of capability names or numbers.
+// Surrounding spaces are ignored, names are case-insensitive.
+func parseCapabilities[E capabilityType](str string) (map[E]bool, error) {
+	set := map[E]bool{}
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return set, nil
+	}
+	for _, name := range strings.Split(str, ",") {
+		capability, err := parseCapability[E](strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		set[capability] = true
+	}
+	return set, nil
+}
+
+func parseCapability[E capabilityType](name string) (E, error) {
+	var zero E
+	values := zero.Descriptor().Values()
+	if desc := values.ByName(protoreflect.Name(strings.ToUpper(name))); desc != nil {
+		return E(desc.Number()), nil
+	}
+	if number, err := strconv.ParseInt(name, 10, 32); err == nil {
+		return E(number), nil
+	}
+	return zero, fmt.Errorf("unknown %s %q", zero.Descriptor().FullName(), name)
+}
+
+func unmarshalCapabilities[E capabilityType](data []byte, set *map[E]bool) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	result := make(map[E]bool, len(list))
+	for _, name := range list {
+		capability, err := parseCapability[E](name)
+		if err != nil {
+			return err
+		}
+		result[capability] = true
+	}
+	*set = result
+	return nil
+}
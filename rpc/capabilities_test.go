@@ -0,0 +1,107 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapabilitySetString(t *testing.T) {
+	caps := ControllerCapabilitySet{
+		csi.ControllerServiceCapability_RPC_LIST_VOLUMES:         true,
+		csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME: true,
+		csi.ControllerServiceCapability_RPC_GET_CAPACITY:         false,
+		1000: true,
+	}
+	assert.Equal(t, []string{"1000", "CREATE_DELETE_VOLUME", "LIST_VOLUMES"}, caps.List())
+	assert.Equal(t, "1000,CREATE_DELETE_VOLUME,LIST_VOLUMES", caps.String())
+	assert.Equal(t, "1000,CREATE_DELETE_VOLUME,LIST_VOLUMES", fmt.Sprintf("%v", caps))
+	assert.Equal(t, "", NodeCapabilitySet(nil).String())
+
+	data, err := json.Marshal(Capabilities{
+		Plugin:     PluginCapabilitySet{csi.PluginCapability_Service_CONTROLLER_SERVICE: true},
+		Controller: caps,
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Plugin":["CONTROLLER_SERVICE"],"Controller":["1000","CREATE_DELETE_VOLUME","LIST_VOLUMES"],"GroupController":[]}`, string(data))
+
+	var decoded Capabilities
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, PluginCapabilitySet{csi.PluginCapability_Service_CONTROLLER_SERVICE: true}, decoded.Plugin)
+	assert.Equal(t, ControllerCapabilitySet{
+		csi.ControllerServiceCapability_RPC_LIST_VOLUMES:         true,
+		csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME: true,
+		1000: true,
+	}, decoded.Controller)
+	assert.Equal(t, GroupControllerCapabilitySet{}, decoded.GroupController)
+
+	assert.Error(t, json.Unmarshal([]byte(`["NO_SUCH_CAPABILITY"]`), &decoded.Controller))
+}
+
+func TestParseCapabilitySet(t *testing.T) {
+	testcases := map[string]struct {
+		input       string
+		expect      NodeCapabilitySet
+		expectError string
+	}{
+		"empty": {
+			input:  " ",
+			expect: NodeCapabilitySet{},
+		},
+		"names": {
+			input: "STAGE_UNSTAGE_VOLUME, get_volume_stats",
+			expect: NodeCapabilitySet{
+				csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME: true,
+				csi.NodeServiceCapability_RPC_GET_VOLUME_STATS:     true,
+			},
+		},
+		"number": {
+			input:  "1000",
+			expect: NodeCapabilitySet{1000: true},
+		},
+		"unknown": {
+			input:       "STAGE_UNSTAGE_VOLUME,NO_SUCH_CAPABILITY",
+			expectError: `unknown csi.v1.NodeServiceCapability.RPC.Type "NO_SUCH_CAPABILITY"`,
+		},
+		"empty entry": {
+			input:       "STAGE_UNSTAGE_VOLUME,",
+			expectError: `unknown csi.v1.NodeServiceCapability.RPC.Type ""`,
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			caps, err := ParseNodeCapabilitySet(tc.input)
+			if tc.expectError != "" {
+				assert.EqualError(t, err, tc.expectError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expect, caps)
+
+			roundtrip, err := ParseNodeCapabilitySet(caps.String())
+			require.NoError(t, err)
+			assert.Equal(t, caps, roundtrip)
+		})
+	}
+}
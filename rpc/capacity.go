@@ -0,0 +1,180 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Common units for RoundBytes. Binary and decimal units are easily
+// confused: 1GiB is 1073741824 bytes, 1GB is 1000000000 bytes.
+const (
+	KiB int64 = 1 << 10
+	MiB int64 = 1 << 20
+	GiB int64 = 1 << 30
+	TiB int64 = 1 << 40
+
+	KB int64 = 1000
+	MB int64 = 1000 * KB
+	GB int64 = 1000 * MB
+	TB int64 = 1000 * GB
+)
+
+// RoundingMode defines how RoundBytes handles sizes which are not a
+// multiple of the unit.
+type RoundingMode int
+
+const (
+	// RoundUp rounds to the next multiple of the unit. Use it for
+	// minimum sizes like required_bytes.
+	RoundUp RoundingMode = iota
+	// RoundDown rounds to the previous multiple of the unit. Use it
+	// for maximum sizes like limit_bytes.
+	RoundDown
+	// RoundExact rejects sizes which are not a multiple of the unit.
+	RoundExact
+)
+
+func (m RoundingMode) String() string {
+	switch m {
+	case RoundUp:
+		return "up"
+	case RoundDown:
+		return "down"
+	case RoundExact:
+		return "exact"
+	default:
+		return fmt.Sprintf("RoundingMode(%d)", int(m))
+	}
+}
+
+// RoundBytes rounds a size in bytes to a multiple of the unit.
+// Negative sizes, units smaller than one and overflows are errors.
+func RoundBytes(bytes, unit int64, mode RoundingMode) (int64, error) {
+	if bytes < 0 {
+		return 0, fmt.Errorf("size %d must not be negative", bytes)
+	}
+	if unit < 1 {
+		return 0, fmt.Errorf("unit %d must be positive", unit)
+	}
+	remainder := bytes % unit
+	if remainder == 0 {
+		return bytes, nil
+	}
+	switch mode {
+	case RoundUp:
+		if bytes > math.MaxInt64-(unit-remainder) {
+			return 0, fmt.Errorf("rounding size %d up to a multiple of %d overflows", bytes, unit)
+		}
+		return bytes + unit - remainder, nil
+	case RoundDown:
+		return bytes - remainder, nil
+	case RoundExact:
+		return 0, fmt.Errorf("size %d is not a multiple of %d", bytes, unit)
+	default:
+		return 0, fmt.Errorf("unknown rounding mode %s", mode)
+	}
+}
+
+// QuantityToBytes converts a quantity into bytes. Fractions of a byte
+// are rounded up. Negative quantities and quantities which do not fit
+// into an int64 are errors.
+func QuantityToBytes(q resource.Quantity) (int64, error) {
+	if q.Sign() < 0 {
+		return 0, fmt.Errorf("quantity %s must not be negative", q.String())
+	}
+	if q.Cmp(*resource.NewQuantity(math.MaxInt64, resource.BinarySI)) > 0 {
+		return 0, fmt.Errorf("quantity %s is too large", q.String())
+	}
+	return q.Value(), nil
+}
+
+// BytesToQuantity converts bytes, for example the capacity_bytes of a
+// volume or the available_capacity of GetCapacity, into a quantity
+// which is formatted with binary suffixes like Gi.
+func BytesToQuantity(bytes int64) resource.Quantity {
+	return *resource.NewQuantity(bytes, resource.BinarySI)
+}
+
+// ValidateCapacityRange checks that the values are not negative and
+// that required_bytes is not larger than limit_bytes when both are set.
+// A nil range is valid.
+func ValidateCapacityRange(capacityRange *csi.CapacityRange) error {
+	required := capacityRange.GetRequiredBytes()
+	limit := capacityRange.GetLimitBytes()
+	var errs []error
+	if required < 0 {
+		errs = append(errs, fmt.Errorf("required_bytes %d must not be negative", required))
+	}
+	if limit < 0 {
+		errs = append(errs, fmt.Errorf("limit_bytes %d must not be negative", limit))
+	}
+	if required > 0 && limit > 0 && required > limit {
+		errs = append(errs, fmt.Errorf("required_bytes %d must not be larger than limit_bytes %d", required, limit))
+	}
+	return errors.Join(errs...)
+}
+
+// NewCapacityRange converts the storage request and limit of a
+// PersistentVolumeClaim into a capacity range for CreateVolume or
+// ControllerExpandVolume. The request is mandatory, the limit optional.
+//
+// The request is rounded up and the limit rounded down to a multiple
+// of unit, typically the allocation granularity of the storage system.
+// Use 1 to disable rounding. It is an error if the rounded request
+// exceeds the rounded limit.
+func NewCapacityRange(resources v1.VolumeResourceRequirements, unit int64) (*csi.CapacityRange, error) {
+	request, ok := resources.Requests[v1.ResourceStorage]
+	if !ok {
+		return nil, errors.New("storage request is missing")
+	}
+	required, err := QuantityToBytes(request)
+	if err != nil {
+		return nil, fmt.Errorf("storage request: %w", err)
+	}
+	required, err = RoundBytes(required, unit, RoundUp)
+	if err != nil {
+		return nil, fmt.Errorf("storage request: %w", err)
+	}
+	capacityRange := &csi.CapacityRange{RequiredBytes: required}
+
+	if limitQuantity, ok := resources.Limits[v1.ResourceStorage]; ok {
+		limit, err := QuantityToBytes(limitQuantity)
+		if err != nil {
+			return nil, fmt.Errorf("storage limit: %w", err)
+		}
+		limit, err = RoundBytes(limit, unit, RoundDown)
+		if err != nil {
+			return nil, fmt.Errorf("storage limit: %w", err)
+		}
+		if limit == 0 {
+			// Zero would mean "no limit" in CSI.
+			return nil, fmt.Errorf("storage limit %s is smaller than the unit %d", limitQuantity.String(), unit)
+		}
+		if required > limit {
+			return nil, fmt.Errorf("storage request %s, rounded up to %d bytes, exceeds the storage limit %s, rounded down to %d bytes", request.String(), required, limitQuantity.String(), limit)
+		}
+		capacityRange.LimitBytes = limit
+	}
+	return capacityRange, nil
+}
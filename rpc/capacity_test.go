@@ -0,0 +1,168 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rpc
+
+import (
+	"math"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestRoundBytes(t *testing.T) {
+	testcases := []struct {
+		bytes, unit int64
+		mode        RoundingMode
+		expect      int64
+		expectError string
+	}{
+		{bytes: GiB, unit: GiB, mode: RoundExact, expect: GiB},
+		{bytes: GB, unit: GiB, mode: RoundUp, expect: GiB},
+		{bytes: GB, unit: GiB, mode: RoundDown, expect: 0},
+		{bytes: GB, unit: GiB, mode: RoundExact, expectError: "size 1000000000 is not a multiple of 1073741824"},
+		{bytes: 5*GiB + 1, unit: GiB, mode: RoundDown, expect: 5 * GiB},
+		{bytes: 0, unit: GiB, mode: RoundUp, expect: 0},
+		{bytes: 1, unit: 1, mode: RoundExact, expect: 1},
+		{bytes: -1, unit: 1, mode: RoundUp, expectError: "size -1 must not be negative"},
+		{bytes: 1, unit: 0, mode: RoundUp, expectError: "unit 0 must be positive"},
+		{bytes: math.MaxInt64, unit: GiB, mode: RoundUp, expectError: "rounding size 9223372036854775807 up to a multiple of 1073741824 overflows"},
+		{bytes: 1, unit: 2, mode: RoundingMode(42), expectError: "unknown rounding mode RoundingMode(42)"},
+	}
+
+	for _, tc := range testcases {
+		actual, err := RoundBytes(tc.bytes, tc.unit, tc.mode)
+		if tc.expectError != "" {
+			assert.EqualError(t, err, tc.expectError, "%+v", tc)
+			continue
+		}
+		if assert.NoError(t, err, "%+v", tc) {
+			assert.Equal(t, tc.expect, actual, "%+v", tc)
+		}
+	}
+}
+
+func TestQuantityToBytes(t *testing.T) {
+	bytes, err := QuantityToBytes(resource.MustParse("1Gi"))
+	require.NoError(t, err)
+	assert.Equal(t, GiB, bytes)
+	bytes, err = QuantityToBytes(resource.MustParse("1G"))
+	require.NoError(t, err)
+	assert.Equal(t, GB, bytes)
+	bytes, err = QuantityToBytes(resource.MustParse("1500m"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), bytes, "fractions are rounded up")
+
+	_, err = QuantityToBytes(resource.MustParse("-1"))
+	assert.Error(t, err, "negative")
+	tooLarge := resource.MustParse("8Ei")
+	tooLarge.Add(resource.MustParse("8Ei"))
+	_, err = QuantityToBytes(tooLarge)
+	assert.Error(t, err, "too large")
+
+	q := BytesToQuantity(5 * GiB)
+	assert.Equal(t, "5Gi", q.String())
+}
+
+func TestValidateCapacityRange(t *testing.T) {
+	assert.NoError(t, ValidateCapacityRange(nil))
+	assert.NoError(t, ValidateCapacityRange(&csi.CapacityRange{RequiredBytes: GiB}))
+	assert.NoError(t, ValidateCapacityRange(&csi.CapacityRange{LimitBytes: GiB}))
+	assert.NoError(t, ValidateCapacityRange(&csi.CapacityRange{RequiredBytes: GiB, LimitBytes: GiB}))
+	assert.EqualError(t, ValidateCapacityRange(&csi.CapacityRange{RequiredBytes: GiB, LimitBytes: GB}),
+		"required_bytes 1073741824 must not be larger than limit_bytes 1000000000")
+	assert.EqualError(t, ValidateCapacityRange(&csi.CapacityRange{RequiredBytes: -1, LimitBytes: -2}),
+		"required_bytes -1 must not be negative\nlimit_bytes -2 must not be negative")
+}
+
+func TestNewCapacityRange(t *testing.T) {
+	resources := func(request, limit string) v1.VolumeResourceRequirements {
+		var r v1.VolumeResourceRequirements
+		if request != "" {
+			r.Requests = v1.ResourceList{v1.ResourceStorage: resource.MustParse(request)}
+		}
+		if limit != "" {
+			r.Limits = v1.ResourceList{v1.ResourceStorage: resource.MustParse(limit)}
+		}
+		return r
+	}
+
+	testcases := map[string]struct {
+		resources   v1.VolumeResourceRequirements
+		unit        int64
+		expect      *csi.CapacityRange
+		expectError string
+	}{
+		"request only": {
+			resources: resources("1G", ""),
+			unit:      1,
+			expect:    &csi.CapacityRange{RequiredBytes: GB},
+		},
+		"request rounded up": {
+			resources: resources("1G", ""),
+			unit:      GiB,
+			expect:    &csi.CapacityRange{RequiredBytes: GiB},
+		},
+		"request and limit": {
+			resources: resources("1Gi", "10G"),
+			unit:      GiB,
+			expect:    &csi.CapacityRange{RequiredBytes: GiB, LimitBytes: 9 * GiB},
+		},
+		"missing request": {
+			resources:   resources("", "1Gi"),
+			unit:        1,
+			expectError: "storage request is missing",
+		},
+		"request exceeds limit after rounding": {
+			resources:   resources("1G", "1000300k"),
+			unit:        MiB,
+			expectError: "storage request 1G, rounded up to 1000341504 bytes, exceeds the storage limit 1000300k, rounded down to 999292928 bytes",
+		},
+		"request exceeds limit": {
+			resources:   resources("2Gi", "1Gi"),
+			unit:        GiB,
+			expectError: "storage request 2Gi, rounded up to 2147483648 bytes, exceeds the storage limit 1Gi, rounded down to 1073741824 bytes",
+		},
+		"limit below unit": {
+			resources:   resources("0", "1G"),
+			unit:        GiB,
+			expectError: "storage limit 1G is smaller than the unit 1073741824",
+		},
+		"invalid unit": {
+			resources:   resources("1G", ""),
+			unit:        0,
+			expectError: "storage request: unit 0 must be positive",
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			capacityRange, err := NewCapacityRange(tc.resources, tc.unit)
+			if tc.expectError != "" {
+				assert.EqualError(t, err, tc.expectError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expect.RequiredBytes, capacityRange.RequiredBytes, "required bytes")
+			assert.Equal(t, tc.expect.LimitBytes, capacityRange.LimitBytes, "limit bytes")
+			assert.NoError(t, ValidateCapacityRange(capacityRange))
+		})
+	}
+}
@@ -0,0 +1,245 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Client wraps a connection to a CSI driver. The capabilities of the
+// driver are discovered once by NewClient and then cached.
+//
+// The gRPC clients returned by IdentityClient, ControllerClient,
+// GroupControllerClient, NodeClient and SnapshotMetadataClient
+// check those capabilities before sending a call. A call which
+// requires a capability that the driver did not advertise fails
+// with an *UnsupportedError, without contacting the driver.
+type Client struct {
+	conn    *grpc.ClientConn
+	checked grpc.ClientConnInterface
+
+	pluginCapabilities          PluginCapabilitySet
+	controllerCapabilities      ControllerCapabilitySet
+	groupControllerCapabilities GroupControllerCapabilitySet
+	nodeCapabilities            NodeCapabilitySet
+}
+
+// NewClient discovers the capabilities of the driver behind the connection.
+//
+// Controller and group controller capabilities are only retrieved if
+// the driver advertises the corresponding plugin capability. The node
+// service is optional, for example when connecting to a controller-only
+// deployment. If it is not implemented, the driver is treated as having
+// no node capabilities.
+func NewClient(ctx context.Context, conn *grpc.ClientConn) (*Client, error) {
+	c := &Client{
+		conn: conn,
+	}
+	c.checked = &checkedConn{conn: conn, client: c}
+
+	caps, err := GetCapabilities(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	c.pluginCapabilities = caps.Plugin
+	c.controllerCapabilities = caps.Controller
+	c.groupControllerCapabilities = caps.GroupController
+	c.nodeCapabilities, err = GetNodeCapabilities(ctx, conn)
+	if err != nil {
+		if status.Code(err) != codes.Unimplemented {
+			return nil, fmt.Errorf("get node capabilities: %w", err)
+		}
+		c.nodeCapabilities = NodeCapabilitySet{}
+	}
+	return c, nil
+}
+
+// Conn returns the underlying connection. Calls made directly through it
+// are not checked against the capabilities.
+func (c *Client) Conn() *grpc.ClientConn {
+	return c.conn
+}
+
+// PluginCapabilities returns the cached plugin capabilities. The result must not be modified.
+func (c *Client) PluginCapabilities() PluginCapabilitySet {
+	return c.pluginCapabilities
+}
+
+// ControllerCapabilities returns the cached controller capabilities. The result must not be modified.
+func (c *Client) ControllerCapabilities() ControllerCapabilitySet {
+	return c.controllerCapabilities
+}
+
+// GroupControllerCapabilities returns the cached group controller capabilities. The result must not be modified.
+func (c *Client) GroupControllerCapabilities() GroupControllerCapabilitySet {
+	return c.groupControllerCapabilities
+}
+
+// NodeCapabilities returns the cached node capabilities. The result must not be modified.
+func (c *Client) NodeCapabilities() NodeCapabilitySet {
+	return c.nodeCapabilities
+}
+
+// Supports checks whether the driver advertised the capability. The
+// capability must be one of csi.PluginCapability_Service_Type,
+// csi.ControllerServiceCapability_RPC_Type,
+// csi.GroupControllerServiceCapability_RPC_Type or
+// csi.NodeServiceCapability_RPC_Type. Any other value is not supported.
+func (c *Client) Supports(capability protoreflect.Enum) bool {
+	switch capability := capability.(type) {
+	case csi.PluginCapability_Service_Type:
+		return c.pluginCapabilities[capability]
+	case csi.ControllerServiceCapability_RPC_Type:
+		return c.controllerCapabilities[capability]
+	case csi.GroupControllerServiceCapability_RPC_Type:
+		return c.groupControllerCapabilities[capability]
+	case csi.NodeServiceCapability_RPC_Type:
+		return c.nodeCapabilities[capability]
+	default:
+		return false
+	}
+}
+
+// CheckMethod returns an *UnsupportedError if the full gRPC method name
+// (for example, "/csi.v1.Controller/CreateVolume") depends on a capability
+// which the driver did not advertise. Unknown methods are allowed.
+func (c *Client) CheckMethod(method string) error {
+	for _, capability := range methodCapabilities[method] {
+		if !c.Supports(capability) {
+			return &UnsupportedError{Method: method, Capability: capability}
+		}
+	}
+	return nil
+}
+
+// IdentityClient returns a client for the CSI Identity service.
+func (c *Client) IdentityClient() csi.IdentityClient {
+	return csi.NewIdentityClient(c.checked)
+}
+
+// ControllerClient returns a client for the CSI Controller service
+// which checks the controller capabilities before each call.
+func (c *Client) ControllerClient() csi.ControllerClient {
+	return csi.NewControllerClient(c.checked)
+}
+
+// GroupControllerClient returns a client for the CSI GroupController service
+// which checks the group controller capabilities before each call.
+func (c *Client) GroupControllerClient() csi.GroupControllerClient {
+	return csi.NewGroupControllerClient(c.checked)
+}
+
+// NodeClient returns a client for the CSI Node service
+// which checks the node capabilities before each call.
+func (c *Client) NodeClient() csi.NodeClient {
+	return csi.NewNodeClient(c.checked)
+}
+
+// SnapshotMetadataClient returns a client for the CSI SnapshotMetadata service
+// which checks that the service is advertised before each call.
+func (c *Client) SnapshotMetadataClient() csi.SnapshotMetadataClient {
+	return csi.NewSnapshotMetadataClient(c.checked)
+}
+
+// UnsupportedError is returned by the clients of a Client for calls
+// which need a capability that the driver did not advertise. It
+// converts to a gRPC status with code Unimplemented, so
+// status.Code(err) works as for an error returned by the driver.
+type UnsupportedError struct {
+	// Method is the full gRPC method name.
+	Method string
+	// Capability is the missing capability.
+	Capability protoreflect.Enum
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("%s: CSI driver does not support capability %s", e.Method, capabilityName(e.Capability))
+}
+
+// GRPCStatus implements the interface checked by status.FromError.
+func (e *UnsupportedError) GRPCStatus() *status.Status {
+	return status.New(codes.Unimplemented, e.Error())
+}
+
+func capabilityName(capability protoreflect.Enum) string {
+	desc := capability.Descriptor().Values().ByNumber(capability.Number())
+	if desc == nil {
+		return fmt.Sprintf("%s(%d)", capability.Descriptor().FullName(), capability.Number())
+	}
+	return string(desc.Name())
+}
+
+// methodCapabilities lists the capabilities which must be advertised
+// before a method may be called.
+var methodCapabilities = map[string][]protoreflect.Enum{
+	csi.Controller_CreateVolume_FullMethodName:               {csi.PluginCapability_Service_CONTROLLER_SERVICE, csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME},
+	csi.Controller_DeleteVolume_FullMethodName:               {csi.PluginCapability_Service_CONTROLLER_SERVICE, csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME},
+	csi.Controller_ControllerPublishVolume_FullMethodName:    {csi.PluginCapability_Service_CONTROLLER_SERVICE, csi.ControllerServiceCapability_RPC_PUBLISH_UNPUBLISH_VOLUME},
+	csi.Controller_ControllerUnpublishVolume_FullMethodName:  {csi.PluginCapability_Service_CONTROLLER_SERVICE, csi.ControllerServiceCapability_RPC_PUBLISH_UNPUBLISH_VOLUME},
+	csi.Controller_ValidateVolumeCapabilities_FullMethodName: {csi.PluginCapability_Service_CONTROLLER_SERVICE},
+	csi.Controller_ListVolumes_FullMethodName:                {csi.PluginCapability_Service_CONTROLLER_SERVICE, csi.ControllerServiceCapability_RPC_LIST_VOLUMES},
+	csi.Controller_GetCapacity_FullMethodName:                {csi.PluginCapability_Service_CONTROLLER_SERVICE, csi.ControllerServiceCapability_RPC_GET_CAPACITY},
+	csi.Controller_ControllerGetCapabilities_FullMethodName:  {csi.PluginCapability_Service_CONTROLLER_SERVICE},
+	csi.Controller_CreateSnapshot_FullMethodName:             {csi.PluginCapability_Service_CONTROLLER_SERVICE, csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT},
+	csi.Controller_DeleteSnapshot_FullMethodName:             {csi.PluginCapability_Service_CONTROLLER_SERVICE, csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT},
+	csi.Controller_ListSnapshots_FullMethodName:              {csi.PluginCapability_Service_CONTROLLER_SERVICE, csi.ControllerServiceCapability_RPC_LIST_SNAPSHOTS},
+	csi.Controller_ControllerExpandVolume_FullMethodName:     {csi.PluginCapability_Service_CONTROLLER_SERVICE, csi.ControllerServiceCapability_RPC_EXPAND_VOLUME},
+	csi.Controller_ControllerGetVolume_FullMethodName:        {csi.PluginCapability_Service_CONTROLLER_SERVICE, csi.ControllerServiceCapability_RPC_GET_VOLUME},
+	csi.Controller_ControllerModifyVolume_FullMethodName:     {csi.PluginCapability_Service_CONTROLLER_SERVICE, csi.ControllerServiceCapability_RPC_MODIFY_VOLUME},
+
+	csi.GroupController_GroupControllerGetCapabilities_FullMethodName: {csi.PluginCapability_Service_GROUP_CONTROLLER_SERVICE},
+	csi.GroupController_CreateVolumeGroupSnapshot_FullMethodName:      {csi.PluginCapability_Service_GROUP_CONTROLLER_SERVICE, csi.GroupControllerServiceCapability_RPC_CREATE_DELETE_GET_VOLUME_GROUP_SNAPSHOT},
+	csi.GroupController_DeleteVolumeGroupSnapshot_FullMethodName:      {csi.PluginCapability_Service_GROUP_CONTROLLER_SERVICE, csi.GroupControllerServiceCapability_RPC_CREATE_DELETE_GET_VOLUME_GROUP_SNAPSHOT},
+	csi.GroupController_GetVolumeGroupSnapshot_FullMethodName:         {csi.PluginCapability_Service_GROUP_CONTROLLER_SERVICE, csi.GroupControllerServiceCapability_RPC_CREATE_DELETE_GET_VOLUME_GROUP_SNAPSHOT},
+
+	csi.SnapshotMetadata_GetMetadataAllocated_FullMethodName: {csi.PluginCapability_Service_SNAPSHOT_METADATA_SERVICE},
+	csi.SnapshotMetadata_GetMetadataDelta_FullMethodName:     {csi.PluginCapability_Service_SNAPSHOT_METADATA_SERVICE},
+
+	csi.Node_NodeStageVolume_FullMethodName:    {csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME},
+	csi.Node_NodeUnstageVolume_FullMethodName:  {csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME},
+	csi.Node_NodeGetVolumeStats_FullMethodName: {csi.NodeServiceCapability_RPC_GET_VOLUME_STATS},
+	csi.Node_NodeExpandVolume_FullMethodName:   {csi.NodeServiceCapability_RPC_EXPAND_VOLUME},
+}
+
+// checkedConn calls CheckMethod before passing calls on to the real connection.
+type checkedConn struct {
+	conn   *grpc.ClientConn
+	client *Client
+}
+
+var _ grpc.ClientConnInterface = &checkedConn{}
+
+func (cc *checkedConn) Invoke(ctx context.Context, method string, args any, reply any, opts ...grpc.CallOption) error {
+	if err := cc.client.CheckMethod(method); err != nil {
+		return err
+	}
+	return cc.conn.Invoke(ctx, method, args, reply, opts...)
+}
+
+func (cc *checkedConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if err := cc.client.CheckMethod(method); err != nil {
+		return nil, err
+	}
+	return cc.conn.NewStream(ctx, desc, method, opts...)
+}
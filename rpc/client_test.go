@@ -0,0 +1,207 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubernetes-csi/csi-lib-utils/connection"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2/ktesting"
+)
+
+func pluginCapabilitiesResponse(types ...csi.PluginCapability_Service_Type) *csi.GetPluginCapabilitiesResponse {
+	rsp := &csi.GetPluginCapabilitiesResponse{}
+	for _, t := range types {
+		rsp.Capabilities = append(rsp.Capabilities, &csi.PluginCapability{
+			Type: &csi.PluginCapability_Service_{
+				Service: &csi.PluginCapability_Service{Type: t},
+			},
+		})
+	}
+	return rsp
+}
+
+func controllerCapabilitiesResponse(types ...csi.ControllerServiceCapability_RPC_Type) *csi.ControllerGetCapabilitiesResponse {
+	rsp := &csi.ControllerGetCapabilitiesResponse{}
+	for _, t := range types {
+		rsp.Capabilities = append(rsp.Capabilities, &csi.ControllerServiceCapability{
+			Type: &csi.ControllerServiceCapability_Rpc{
+				Rpc: &csi.ControllerServiceCapability_RPC{Type: t},
+			},
+		})
+	}
+	return rsp
+}
+
+func nodeCapabilitiesResponse(types ...csi.NodeServiceCapability_RPC_Type) *csi.NodeGetCapabilitiesResponse {
+	rsp := &csi.NodeGetCapabilitiesResponse{}
+	for _, t := range types {
+		rsp.Capabilities = append(rsp.Capabilities, &csi.NodeServiceCapability{
+			Type: &csi.NodeServiceCapability_Rpc{
+				Rpc: &csi.NodeServiceCapability_RPC{Type: t},
+			},
+		})
+	}
+	return rsp
+}
+
+func TestClient(t *testing.T) {
+	tmp := tmpDir(t)
+	defer os.RemoveAll(tmp)
+	identity := &fakeIdentityServer{
+		getPluginCapabilitiesResponse: pluginCapabilitiesResponse(csi.PluginCapability_Service_CONTROLLER_SERVICE),
+	}
+	controller := &fakeControllerServer{
+		controllerGetCapabilitiesResponse: controllerCapabilitiesResponse(csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME),
+	}
+	node := &fakeNodeServer{
+		nodeGetCapabilitiesResponse: nodeCapabilitiesResponse(csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME),
+	}
+	addr, stopServer := startServer(t, tmp, identity, controller, nil, node)
+	defer stopServer()
+
+	_, ctx := ktesting.NewTestContext(t)
+	conn, err := connection.Connect(ctx, addr, nil)
+	require.NoError(t, err, "connect")
+	defer conn.Close()
+
+	client, err := NewClient(ctx, conn)
+	require.NoError(t, err, "NewClient")
+
+	assert.Equal(t, PluginCapabilitySet{csi.PluginCapability_Service_CONTROLLER_SERVICE: true}, client.PluginCapabilities())
+	assert.Equal(t, ControllerCapabilitySet{csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME: true}, client.ControllerCapabilities())
+	assert.Equal(t, GroupControllerCapabilitySet{}, client.GroupControllerCapabilities())
+	assert.Equal(t, NodeCapabilitySet{csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME: true}, client.NodeCapabilities())
+
+	assert.True(t, client.Supports(csi.PluginCapability_Service_CONTROLLER_SERVICE))
+	assert.False(t, client.Supports(csi.PluginCapability_Service_GROUP_CONTROLLER_SERVICE))
+	assert.True(t, client.Supports(csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME))
+	assert.False(t, client.Supports(csi.ControllerServiceCapability_RPC_LIST_VOLUMES))
+	assert.True(t, client.Supports(csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME))
+	assert.False(t, client.Supports(csi.NodeServiceCapability_RPC_GET_VOLUME_STATS))
+	assert.False(t, client.Supports(csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER), "unknown enum type")
+
+	// Supported calls reach the fake driver, which doesn't implement them.
+	_, err = client.ControllerClient().CreateVolume(ctx, &csi.CreateVolumeRequest{})
+	assertUnimplemented(t, err, false)
+	_, err = client.NodeClient().NodeStageVolume(ctx, &csi.NodeStageVolumeRequest{})
+	assertUnimplemented(t, err, false)
+	_, err = client.IdentityClient().GetPluginInfo(ctx, &csi.GetPluginInfoRequest{})
+	assert.NoError(t, err, "GetPluginInfo")
+
+	// Unsupported calls get rejected locally.
+	_, err = client.ControllerClient().ListVolumes(ctx, &csi.ListVolumesRequest{})
+	assertUnimplemented(t, err, true)
+	_, err = client.NodeClient().NodeGetVolumeStats(ctx, &csi.NodeGetVolumeStatsRequest{})
+	assertUnimplemented(t, err, true)
+	_, err = client.GroupControllerClient().CreateVolumeGroupSnapshot(ctx, &csi.CreateVolumeGroupSnapshotRequest{})
+	assertUnimplemented(t, err, true)
+	_, err = client.SnapshotMetadataClient().GetMetadataAllocated(ctx, &csi.GetMetadataAllocatedRequest{})
+	assertUnimplemented(t, err, true)
+	assert.EqualError(t, err, "/csi.v1.SnapshotMetadata/GetMetadataAllocated: CSI driver does not support capability SNAPSHOT_METADATA_SERVICE")
+}
+
+func assertUnimplemented(t *testing.T, err error, local bool) {
+	t.Helper()
+	assert.Equal(t, codes.Unimplemented, status.Code(err), "gRPC status code of %v", err)
+	var unsupported *UnsupportedError
+	assert.Equal(t, local, errors.As(err, &unsupported), "UnsupportedError: %v", err)
+}
+
+func TestNewClient(t *testing.T) {
+	tests := []struct {
+		name        string
+		identity    *fakeIdentityServer
+		controller  *fakeControllerServer
+		node        *fakeNodeServer
+		expectError bool
+	}{
+		{
+			name: "no controller and node service",
+			identity: &fakeIdentityServer{
+				getPluginCapabilitiesResponse: pluginCapabilitiesResponse(),
+			},
+		},
+		{
+			name: "plugin capabilities error",
+			identity: &fakeIdentityServer{
+				err: fmt.Errorf("mock error"),
+			},
+			expectError: true,
+		},
+		{
+			name: "controller capabilities error",
+			identity: &fakeIdentityServer{
+				getPluginCapabilitiesResponse: pluginCapabilitiesResponse(csi.PluginCapability_Service_CONTROLLER_SERVICE),
+			},
+			controller: &fakeControllerServer{
+				err: fmt.Errorf("mock error"),
+			},
+			expectError: true,
+		},
+		{
+			name: "node capabilities error",
+			identity: &fakeIdentityServer{
+				getPluginCapabilitiesResponse: pluginCapabilitiesResponse(),
+			},
+			node: &fakeNodeServer{
+				err: status.Error(codes.Internal, "mock error"),
+			},
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tmp := tmpDir(t)
+			defer os.RemoveAll(tmp)
+			var controller csi.ControllerServer
+			if test.controller != nil {
+				controller = test.controller
+			}
+			var node csi.NodeServer
+			if test.node != nil {
+				node = test.node
+			}
+			addr, stopServer := startServer(t, tmp, test.identity, controller, nil, node)
+			defer stopServer()
+
+			_, ctx := ktesting.NewTestContext(t)
+			conn, err := connection.Connect(ctx, addr, nil)
+			require.NoError(t, err, "connect")
+			defer conn.Close()
+
+			client, err := NewClient(ctx, conn)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Empty(t, client.ControllerCapabilities())
+				assert.Empty(t, client.NodeCapabilities())
+			}
+		})
+	}
+}
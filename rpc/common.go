@@ -18,16 +18,17 @@ package rpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
-
-	"k8s.io/klog/v2"
 )
 
 const (
@@ -51,6 +52,58 @@ func GetDriverName(ctx context.Context, conn *grpc.ClientConn) (string, error) {
 	return name, nil
 }
 
+// PluginInfo is the validated result of GetPluginInfo.
+type PluginInfo struct {
+	// Name is the name of the CSI driver.
+	Name string
+	// VendorVersion is the version of the driver, may be empty.
+	VendorVersion string
+	// Manifest contains additional, driver-specific information, may be nil.
+	Manifest map[string]string
+}
+
+// GetPluginInfo returns name, version and manifest of CSI driver.
+// In contrast to GetDriverName, the name is also checked with
+// ValidateDriverName.
+func GetPluginInfo(ctx context.Context, conn *grpc.ClientConn) (*PluginInfo, error) {
+	client := csi.NewIdentityClient(conn)
+
+	req := csi.GetPluginInfoRequest{}
+	rsp, err := client.GetPluginInfo(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateDriverName(rsp.GetName()); err != nil {
+		return nil, err
+	}
+	return &PluginInfo{
+		Name:          rsp.GetName(),
+		VendorVersion: rsp.GetVendorVersion(),
+		Manifest:      rsp.GetManifest(),
+	}, nil
+}
+
+// maxDriverNameLength is the limit for the driver name in the CSI spec.
+const maxDriverNameLength = 63
+
+// driverNameRE implements the character rules for the driver name in the CSI spec.
+var driverNameRE = regexp.MustCompile(`^[a-zA-Z0-9]([-.a-zA-Z0-9]*[a-zA-Z0-9])?$`)
+
+// ValidateDriverName checks a driver name against the rules in the CSI spec:
+// it must be 63 characters or less, begin and end with an alphanumeric
+// character and contain only dashes, dots and alphanumerics in between.
+func ValidateDriverName(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("driver name is empty")
+	case len(name) > maxDriverNameLength:
+		return fmt.Errorf("driver name %q is longer than %d characters", name, maxDriverNameLength)
+	case !driverNameRE.MatchString(name):
+		return fmt.Errorf("driver name %q must begin and end with an alphanumeric character and contain only dashes, dots and alphanumerics in between", name)
+	}
+	return nil
+}
+
 // PluginCapabilitySet is set of CSI plugin capabilities. Only supported capabilities are in the map.
 type PluginCapabilitySet map[csi.PluginCapability_Service_Type]bool
 
@@ -131,42 +184,125 @@ func GetGroupControllerCapabilities(ctx context.Context, conn *grpc.ClientConn)
 	return caps, nil
 }
 
-// ProbeForever calls Probe() of a CSI driver and waits until the driver becomes ready.
-// Any error other than timeout is returned.
-func ProbeForever(ctx context.Context, conn *grpc.ClientConn, singleProbeTimeout time.Duration) error {
-	logger := klog.FromContext(ctx)
-	ticker := time.NewTicker(probeInterval)
-	defer ticker.Stop()
-
-	for {
-		// Run the probe once before waiting for the ticker
-		logger.Info("Probing CSI driver for readiness")
-		ready, err := probeOnce(ctx, conn, singleProbeTimeout)
-		if err != nil {
-			st, ok := status.FromError(err)
-			if !ok {
-				// This is not gRPC error. The probe must have failed before gRPC
-				// method was called, otherwise we would get gRPC error.
-				return fmt.Errorf("CSI driver probe failed: %s", err)
-			}
-			if st.Code() != codes.DeadlineExceeded {
-				return fmt.Errorf("CSI driver probe failed: %s", err)
-			}
-			// Timeout -> driver is not ready. Fall through to sleep() below.
-			logger.Info("CSI driver probe timed out")
-		} else {
-			if ready {
-				return nil
-			}
-			logger.Info("CSI driver is not ready")
+// NodeCapabilitySet is set of CSI node capabilities. Only supported capabilities are in the map.
+type NodeCapabilitySet map[csi.NodeServiceCapability_RPC_Type]bool
+
+// GetNodeCapabilities returns set of supported node capabilities of CSI driver.
+func GetNodeCapabilities(ctx context.Context, conn *grpc.ClientConn) (NodeCapabilitySet, error) {
+	client := csi.NewNodeClient(conn)
+	req := csi.NodeGetCapabilitiesRequest{}
+	rsp, err := client.NodeGetCapabilities(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	caps := NodeCapabilitySet{}
+	for _, cap := range rsp.GetCapabilities() {
+		if cap == nil {
+			continue
 		}
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-ticker.C:
+		rpc := cap.GetRpc()
+		if rpc == nil {
 			continue
 		}
+		t := rpc.GetType()
+		caps[t] = true
 	}
+	return caps, nil
+}
+
+// NodeInfo is the validated result of NodeGetInfo.
+type NodeInfo struct {
+	// NodeID identifies the node in the CSI driver. It is never empty.
+	NodeID string
+	// MaxVolumesPerNode is the maximum number of volumes that can be
+	// published to the node, zero if not set by the driver.
+	MaxVolumesPerNode int64
+	// AccessibleTopology contains the topology segments of the node,
+	// nil if the driver did not return any.
+	AccessibleTopology map[string]string
+}
+
+// GetNodeInfo calls NodeGetInfo and checks the response. The node ID
+// must not be empty, the maximum number of volumes must not be negative
+// and the topology must follow the rules from the CSI spec for keys and
+// values.
+func GetNodeInfo(ctx context.Context, conn *grpc.ClientConn) (*NodeInfo, error) {
+	client := csi.NewNodeClient(conn)
+	req := csi.NodeGetInfoRequest{}
+	rsp, err := client.NodeGetInfo(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &NodeInfo{
+		NodeID:            rsp.GetNodeId(),
+		MaxVolumesPerNode: rsp.GetMaxVolumesPerNode(),
+	}
+	if info.NodeID == "" {
+		return nil, fmt.Errorf("node ID is empty")
+	}
+	if info.MaxVolumesPerNode < 0 {
+		return nil, fmt.Errorf("max volumes per node is negative: %d", info.MaxVolumesPerNode)
+	}
+	if topology := rsp.GetAccessibleTopology(); topology != nil {
+		if err := validateTopology(topology.GetSegments()); err != nil {
+			return nil, fmt.Errorf("invalid accessible topology: %w", err)
+		}
+		info.AccessibleTopology = topology.GetSegments()
+	}
+	return info, nil
+}
+
+var (
+	// topologyNameRE matches the key name and the values of topology segments.
+	topologyNameRE = regexp.MustCompile(`^[a-zA-Z0-9]([-_.a-zA-Z0-9]*[a-zA-Z0-9])?$`)
+	// topologyPrefixRE matches the optional key prefix of topology segments.
+	topologyPrefixRE = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+)
+
+const maxTopologyStringLength = 63
+
+// validateTopology checks topology segments against the rules in the
+// description of the Topology message in the CSI spec.
+func validateTopology(segments map[string]string) error {
+	var errs []error
+	prefixes := map[string]bool{}
+	lowerKeys := map[string]string{}
+	for _, key := range slices.Sorted(maps.Keys(segments)) {
+		prefix, name, hasPrefix := strings.Cut(key, "/")
+		if !hasPrefix {
+			name = prefix
+			prefix = ""
+		}
+		if hasPrefix {
+			if len(prefix) > maxTopologyStringLength || !topologyPrefixRE.MatchString(prefix) {
+				errs = append(errs, fmt.Errorf("key %q: prefix must be a lower-case DNS name with at most %d characters", key, maxTopologyStringLength))
+			}
+			prefixes[prefix] = true
+		}
+		if len(name) > maxTopologyStringLength || !topologyNameRE.MatchString(name) {
+			errs = append(errs, fmt.Errorf("key %q: name must consist of at most %d alphanumeric characters, '-', '_' or '.', starting and ending with an alphanumeric character", key, maxTopologyStringLength))
+		}
+		if other, ok := lowerKeys[strings.ToLower(key)]; ok {
+			errs = append(errs, fmt.Errorf("key %q: conflicts with key %q, keys are case-insensitive", key, other))
+		}
+		lowerKeys[strings.ToLower(key)] = key
+		if value := segments[key]; len(value) > maxTopologyStringLength || !topologyNameRE.MatchString(value) {
+			errs = append(errs, fmt.Errorf("key %q: value %q must consist of at most %d alphanumeric characters, '-', '_' or '.', starting and ending with an alphanumeric character", key, value, maxTopologyStringLength))
+		}
+	}
+	if len(prefixes) > 1 {
+		errs = append(errs, fmt.Errorf("keys must all use the same prefix, got %s", strings.Join(slices.Sorted(maps.Keys(prefixes)), ", ")))
+	}
+	return errors.Join(errs...)
+}
+
+// ProbeForever calls Probe() of a CSI driver and waits until the driver becomes ready.
+// Any error other than timeout is returned. It probes once per second.
+// Use ProbeForeverWithOptions for different retry behavior.
+func ProbeForever(ctx context.Context, conn *grpc.ClientConn, singleProbeTimeout time.Duration) error {
+	return ProbeForeverWithOptions(ctx, conn, singleProbeTimeout)
 }
 
 // probeOnce is a helper to simplify defer cancel()
@@ -23,6 +23,7 @@ import (
 	"os"
 	"path"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -54,7 +55,7 @@ const (
 // startServer creates a gRPC server without any registered services.
 // The returned address can be used to connect to it. The cleanup
 // function stops it. It can be called multiple times.
-func startServer(t *testing.T, tmp string, identity csi.IdentityServer, controller csi.ControllerServer, groupCtrl csi.GroupControllerServer) (string, func()) {
+func startServer(t *testing.T, tmp string, identity csi.IdentityServer, controller csi.ControllerServer, groupCtrl csi.GroupControllerServer, node csi.NodeServer) (string, func()) {
 	addr := path.Join(tmp, serverSock)
 	listener, err := net.Listen("unix", addr)
 	require.NoError(t, err, "listening on %s", addr)
@@ -68,6 +69,9 @@ func startServer(t *testing.T, tmp string, identity csi.IdentityServer, controll
 	if groupCtrl != nil {
 		csi.RegisterGroupControllerServer(server, groupCtrl)
 	}
+	if node != nil {
+		csi.RegisterNodeServer(server, node)
+	}
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
@@ -132,7 +136,7 @@ func TestGetDriverName(t *testing.T) {
 				pluginInfoResponse: out,
 				err:                injectedErr,
 			}
-			addr, stopServer := startServer(t, tmp, identity, nil, nil)
+			addr, stopServer := startServer(t, tmp, identity, nil, nil, nil)
 			defer func() {
 				stopServer()
 			}()
@@ -157,6 +161,123 @@ func TestGetDriverName(t *testing.T) {
 	}
 }
 
+func TestGetPluginInfo(t *testing.T) {
+	tests := []struct {
+		name        string
+		output      *csi.GetPluginInfoResponse
+		injectError bool
+		expectInfo  *PluginInfo
+		expectError bool
+	}{
+		{
+			name: "success",
+			output: &csi.GetPluginInfoResponse{
+				Name:          "csi.example.com",
+				VendorVersion: "0.2.0",
+				Manifest: map[string]string{
+					"hello": "world",
+				},
+			},
+			expectInfo: &PluginInfo{
+				Name:          "csi.example.com",
+				VendorVersion: "0.2.0",
+				Manifest: map[string]string{
+					"hello": "world",
+				},
+			},
+		},
+		{
+			name: "only name",
+			output: &csi.GetPluginInfoResponse{
+				Name: "csi-example",
+			},
+			expectInfo: &PluginInfo{
+				Name: "csi-example",
+			},
+		},
+		{
+			name:        "gRPC error",
+			output:      nil,
+			injectError: true,
+			expectError: true,
+		},
+		{
+			name: "empty name",
+			output: &csi.GetPluginInfoResponse{
+				Name: "",
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid name",
+			output: &csi.GetPluginInfoResponse{
+				Name: "csi/example",
+			},
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var injectedErr error
+			if test.injectError {
+				injectedErr = fmt.Errorf("mock error")
+			}
+
+			tmp := tmpDir(t)
+			defer os.RemoveAll(tmp)
+			identity := &fakeIdentityServer{
+				pluginInfoResponse: test.output,
+				err:                injectedErr,
+			}
+			addr, stopServer := startServer(t, tmp, identity, nil, nil, nil)
+			defer func() {
+				stopServer()
+			}()
+
+			_, ctx := ktesting.NewTestContext(t)
+			conn, err := connection.Connect(ctx, addr, metrics.NewCSIMetricsManager("fake.csi.driver.io"))
+			if err != nil {
+				t.Fatalf("Failed to connect to CSI driver: %s", err)
+			}
+
+			info, err := GetPluginInfo(ctx, conn)
+			if test.expectError && err == nil {
+				t.Errorf("Expected error, got none")
+			}
+			if !test.expectError && err != nil {
+				t.Errorf("Got error: %v", err)
+			}
+			if !reflect.DeepEqual(test.expectInfo, info) {
+				t.Errorf("expected info %+v, got %+v", test.expectInfo, info)
+			}
+		})
+	}
+}
+
+func TestValidateDriverName(t *testing.T) {
+	tests := map[string]string{
+		"csi.example.com":       "",
+		"a":                     "",
+		"CSI-Example.com":       "",
+		"":                      "driver name is empty",
+		"csi/example":           `driver name "csi/example" must begin and end with an alphanumeric character and contain only dashes, dots and alphanumerics in between`,
+		"-csi.example.com":      `driver name "-csi.example.com" must begin and end with an alphanumeric character and contain only dashes, dots and alphanumerics in between`,
+		"csi_example":           `driver name "csi_example" must begin and end with an alphanumeric character and contain only dashes, dots and alphanumerics in between`,
+		strings.Repeat("a", 63): "",
+		strings.Repeat("a", 64): fmt.Sprintf("driver name %q is longer than 63 characters", strings.Repeat("a", 64)),
+	}
+	for name, expectError := range tests {
+		err := ValidateDriverName(name)
+		if expectError == "" && err != nil {
+			t.Errorf("%q: unexpected error: %v", name, err)
+		}
+		if expectError != "" && (err == nil || err.Error() != expectError) {
+			t.Errorf("%q: expected error %q, got %v", name, expectError, err)
+		}
+	}
+}
+
 func TestGetPluginCapabilities(t *testing.T) {
 	tests := []struct {
 		name               string
@@ -253,7 +374,7 @@ func TestGetPluginCapabilities(t *testing.T) {
 				// and 1.11.1 (which will be used by new Prow job) via an extra blank line.
 				err: injectedErr,
 			}
-			addr, stopServer := startServer(t, tmp, identity, nil, nil)
+			addr, stopServer := startServer(t, tmp, identity, nil, nil, nil)
 			defer func() {
 				stopServer()
 			}()
@@ -382,7 +503,7 @@ func TestGetControllerCapabilities(t *testing.T) {
 				// and 1.11.1 (which will be used by new Prow job) via an extra blank line.
 				err: injectedErr,
 			}
-			addr, stopServer := startServer(t, tmp, nil, controller, nil)
+			addr, stopServer := startServer(t, tmp, nil, controller, nil, nil)
 			defer func() {
 				stopServer()
 			}()
@@ -477,7 +598,7 @@ func TestGetGroupControllerCapabilities(t *testing.T) {
 				// and 1.11.1 (which will be used by new Prow job) via an extra blank line.
 				err: injectedErr,
 			}
-			addr, stopServer := startServer(t, tmp, nil, nil, groupCtrl)
+			addr, stopServer := startServer(t, tmp, nil, nil, groupCtrl, nil)
 			defer func() {
 				stopServer()
 			}()
@@ -502,6 +623,257 @@ func TestGetGroupControllerCapabilities(t *testing.T) {
 	}
 }
 
+func TestGetNodeCapabilities(t *testing.T) {
+	tests := []struct {
+		name               string
+		output             *csi.NodeGetCapabilitiesResponse
+		injectError        bool
+		expectCapabilities NodeCapabilitySet
+		expectError        bool
+	}{
+		{
+			name: "success",
+			output: &csi.NodeGetCapabilitiesResponse{
+				Capabilities: []*csi.NodeServiceCapability{
+					{
+						Type: &csi.NodeServiceCapability_Rpc{
+							Rpc: &csi.NodeServiceCapability_RPC{
+								Type: csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME,
+							},
+						},
+					},
+					{
+						Type: &csi.NodeServiceCapability_Rpc{
+							Rpc: &csi.NodeServiceCapability_RPC{
+								Type: csi.NodeServiceCapability_RPC_EXPAND_VOLUME,
+							},
+						},
+					},
+				},
+			},
+			expectCapabilities: NodeCapabilitySet{
+				csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME: true,
+				csi.NodeServiceCapability_RPC_EXPAND_VOLUME:        true,
+			},
+			expectError: false,
+		},
+		{
+			name:        "gRPC error",
+			output:      nil,
+			injectError: true,
+			expectError: true,
+		},
+		{
+			name: "empty capability",
+			output: &csi.NodeGetCapabilitiesResponse{
+				Capabilities: []*csi.NodeServiceCapability{
+					{
+						Type: nil,
+					},
+				},
+			},
+			expectCapabilities: NodeCapabilitySet{},
+			expectError:        false,
+		},
+		{
+			name: "no capabilities",
+			output: &csi.NodeGetCapabilitiesResponse{
+				Capabilities: []*csi.NodeServiceCapability{},
+			},
+			expectCapabilities: NodeCapabilitySet{},
+			expectError:        false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var injectedErr error
+			if test.injectError {
+				injectedErr = fmt.Errorf("mock error")
+			}
+
+			tmp := tmpDir(t)
+			defer os.RemoveAll(tmp)
+			node := &fakeNodeServer{
+				nodeGetCapabilitiesResponse: test.output,
+				err:                         injectedErr,
+			}
+			addr, stopServer := startServer(t, tmp, nil, nil, nil, node)
+			defer func() {
+				stopServer()
+			}()
+
+			_, ctx := ktesting.NewTestContext(t)
+			conn, err := connection.Connect(ctx, addr, metrics.NewCSIMetricsManager("fake.csi.driver.io"))
+			if err != nil {
+				t.Fatalf("Failed to connect to CSI driver: %s", err)
+			}
+
+			caps, err := GetNodeCapabilities(ctx, conn)
+			if test.expectError && err == nil {
+				t.Errorf("Expected error, got none")
+			}
+			if !test.expectError && err != nil {
+				t.Errorf("Got error: %v", err)
+			}
+			if !reflect.DeepEqual(test.expectCapabilities, caps) {
+				t.Errorf("expected capabilities %+v, got %+v", test.expectCapabilities, caps)
+			}
+		})
+	}
+}
+
+func TestGetNodeInfo(t *testing.T) {
+	tests := []struct {
+		name        string
+		output      *csi.NodeGetInfoResponse
+		injectError bool
+		expectInfo  *NodeInfo
+		expectError string
+	}{
+		{
+			name: "success",
+			output: &csi.NodeGetInfoResponse{
+				NodeId:            "node-1",
+				MaxVolumesPerNode: 16,
+				AccessibleTopology: &csi.Topology{
+					Segments: map[string]string{
+						"example.com/zone": "zone-1",
+						"example.com/rack": "R_3",
+					},
+				},
+			},
+			expectInfo: &NodeInfo{
+				NodeID:            "node-1",
+				MaxVolumesPerNode: 16,
+				AccessibleTopology: map[string]string{
+					"example.com/zone": "zone-1",
+					"example.com/rack": "R_3",
+				},
+			},
+		},
+		{
+			name: "no topology",
+			output: &csi.NodeGetInfoResponse{
+				NodeId: "node-1",
+			},
+			expectInfo: &NodeInfo{
+				NodeID: "node-1",
+			},
+		},
+		{
+			name:        "gRPC error",
+			injectError: true,
+			expectError: "rpc error: code = Unknown desc = mock error",
+		},
+		{
+			name:        "empty node ID",
+			output:      &csi.NodeGetInfoResponse{},
+			expectError: "node ID is empty",
+		},
+		{
+			name: "negative max volumes",
+			output: &csi.NodeGetInfoResponse{
+				NodeId:            "node-1",
+				MaxVolumesPerNode: -1,
+			},
+			expectError: "max volumes per node is negative: -1",
+		},
+		{
+			name: "invalid key name",
+			output: &csi.NodeGetInfoResponse{
+				NodeId: "node-1",
+				AccessibleTopology: &csi.Topology{
+					Segments: map[string]string{
+						"example.com/-zone": "zone-1",
+					},
+				},
+			},
+			expectError: `invalid accessible topology: key "example.com/-zone": name must consist of at most 63 alphanumeric characters, '-', '_' or '.', starting and ending with an alphanumeric character`,
+		},
+		{
+			name: "invalid prefix",
+			output: &csi.NodeGetInfoResponse{
+				NodeId: "node-1",
+				AccessibleTopology: &csi.Topology{
+					Segments: map[string]string{
+						"Example.com/zone": "zone-1",
+					},
+				},
+			},
+			expectError: `invalid accessible topology: key "Example.com/zone": prefix must be a lower-case DNS name with at most 63 characters`,
+		},
+		{
+			name: "invalid value",
+			output: &csi.NodeGetInfoResponse{
+				NodeId: "node-1",
+				AccessibleTopology: &csi.Topology{
+					Segments: map[string]string{
+						"zone": "",
+					},
+				},
+			},
+			expectError: `invalid accessible topology: key "zone": value "" must consist of at most 63 alphanumeric characters, '-', '_' or '.', starting and ending with an alphanumeric character`,
+		},
+		{
+			name: "different prefixes and case conflict",
+			output: &csi.NodeGetInfoResponse{
+				NodeId: "node-1",
+				AccessibleTopology: &csi.Topology{
+					Segments: map[string]string{
+						"a.example.com/zone": "zone-1",
+						"b.example.com/Zone": "zone-1",
+						"b.example.com/zone": "zone-1",
+					},
+				},
+			},
+			expectError: "invalid accessible topology: key \"b.example.com/zone\": conflicts with key \"b.example.com/Zone\", keys are case-insensitive\nkeys must all use the same prefix, got a.example.com, b.example.com",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var injectedErr error
+			if test.injectError {
+				injectedErr = fmt.Errorf("mock error")
+			}
+
+			tmp := tmpDir(t)
+			defer os.RemoveAll(tmp)
+			node := &fakeNodeServer{
+				nodeGetInfoResponse: test.output,
+				err:                 injectedErr,
+			}
+			addr, stopServer := startServer(t, tmp, nil, nil, nil, node)
+			defer func() {
+				stopServer()
+			}()
+
+			_, ctx := ktesting.NewTestContext(t)
+			conn, err := connection.Connect(ctx, addr, metrics.NewCSIMetricsManager("fake.csi.driver.io"))
+			if err != nil {
+				t.Fatalf("Failed to connect to CSI driver: %s", err)
+			}
+
+			info, err := GetNodeInfo(ctx, conn)
+			if test.expectError != "" {
+				if err == nil {
+					t.Errorf("Expected error, got none")
+				} else if err.Error() != test.expectError {
+					t.Errorf("Expected error %q, got %q", test.expectError, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Got error: %v", err)
+			}
+			if !reflect.DeepEqual(test.expectInfo, info) {
+				t.Errorf("expected info %+v, got %+v", test.expectInfo, info)
+			}
+		})
+	}
+}
+
 func TestProbeForever(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -612,7 +984,7 @@ func TestProbeForever(t *testing.T) {
 			identity := &fakeIdentityServer{
 				probeCalls: test.probeCalls,
 			}
-			addr, stopServer := startServer(t, tmp, identity, nil, nil)
+			addr, stopServer := startServer(t, tmp, identity, nil, nil, nil)
 			defer func() {
 				stopServer()
 			}()
@@ -694,3 +1066,20 @@ var _ csi.GroupControllerServer = &fakeGroupControllerServer{}
 func (c *fakeGroupControllerServer) GroupControllerGetCapabilities(context.Context, *csi.GroupControllerGetCapabilitiesRequest) (*csi.GroupControllerGetCapabilitiesResponse, error) {
 	return c.groupControllerGetCapabilitiesResponse, c.err
 }
+
+type fakeNodeServer struct {
+	csi.UnimplementedNodeServer
+	nodeGetCapabilitiesResponse *csi.NodeGetCapabilitiesResponse
+	nodeGetInfoResponse         *csi.NodeGetInfoResponse
+	err                         error
+}
+
+var _ csi.NodeServer = &fakeNodeServer{}
+
+func (n *fakeNodeServer) NodeGetCapabilities(context.Context, *csi.NodeGetCapabilitiesRequest) (*csi.NodeGetCapabilitiesResponse, error) {
+	return n.nodeGetCapabilitiesResponse, n.err
+}
+
+func (n *fakeNodeServer) NodeGetInfo(context.Context, *csi.NodeGetInfoRequest) (*csi.NodeGetInfoResponse, error) {
+	return n.nodeGetInfoResponse, n.err
+}
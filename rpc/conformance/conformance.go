@@ -0,0 +1,313 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package conformance runs lightweight, non-destructive checks against
+// a CSI driver. Only methods which do not change any state are called:
+// GetPluginInfo, Probe and the various GetCapabilities methods.
+//
+// This is not a replacement for csi-sanity. The intended use is a
+// quick check when a sidecar starts, with the result logged as warning.
+package conformance
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubernetes-csi/csi-lib-utils/rpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"k8s.io/klog/v2"
+)
+
+// Status is the outcome of one check.
+type Status string
+
+const (
+	// StatusPass means that the driver behaves as required by the CSI spec.
+	StatusPass Status = "pass"
+	// StatusWarn means that the driver behaves in an unusual way which
+	// is not necessarily wrong, for example not being ready yet.
+	StatusWarn Status = "warn"
+	// StatusFail means that the driver violates the CSI spec.
+	StatusFail Status = "fail"
+	// StatusSkip means that the check does not apply to the driver.
+	StatusSkip Status = "skip"
+)
+
+// Result describes the outcome of one check.
+type Result struct {
+	// Check identifies the check, for example "GetPluginInfo/name".
+	Check   string `json:"check"`
+	Status  Status `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// Report contains the results of all checks, in the order in which
+// they were run.
+type Report struct {
+	// Driver is the name returned by GetPluginInfo, empty if unknown.
+	Driver  string   `json:"driver,omitempty"`
+	Results []Result `json:"results"`
+}
+
+// Failed returns true if at least one check failed.
+func (r Report) Failed() bool {
+	return len(r.WithStatus(StatusFail)) > 0
+}
+
+// WithStatus returns all results with the given status.
+func (r Report) WithStatus(status Status) []Result {
+	var results []Result
+	for _, result := range r.Results {
+		if result.Status == status {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// String returns one line per result.
+func (r Report) String() string {
+	var b strings.Builder
+	for _, result := range r.Results {
+		fmt.Fprintf(&b, "%s: %s", result.Status, result.Check)
+		if result.Message != "" {
+			fmt.Fprintf(&b, ": %s", result.Message)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Log emits failed checks and warnings as info messages, passed and
+// skipped checks at V(5). This is meant for sidecars which merely want
+// to warn about problems without refusing to start.
+func (r Report) Log(logger klog.Logger) {
+	for _, result := range r.Results {
+		switch result.Status {
+		case StatusFail, StatusWarn:
+			logger.Info("CSI driver conformance check", "driver", r.Driver, "check", result.Check, "status", result.Status, "message", result.Message)
+		default:
+			logger.V(5).Info("CSI driver conformance check", "driver", r.Driver, "check", result.Check, "status", result.Status, "message", result.Message)
+		}
+	}
+}
+
+// Check runs all checks. Errors are reported as failed checks, so the
+// report is always complete. The context is used for all calls; a
+// deadline therefore limits the total duration.
+func Check(ctx context.Context, conn *grpc.ClientConn) Report {
+	c := &checker{conn: conn}
+	c.checkPluginInfo(ctx)
+	c.checkProbe(ctx)
+	pluginCaps := c.checkPluginCapabilities(ctx)
+	c.checkControllerCapabilities(ctx, pluginCaps)
+	c.checkGroupControllerCapabilities(ctx, pluginCaps)
+	c.checkNodeCapabilities(ctx)
+	return c.report
+}
+
+type checker struct {
+	conn   *grpc.ClientConn
+	report Report
+}
+
+func (c *checker) add(check string, status Status, format string, args ...any) {
+	c.report.Results = append(c.report.Results, Result{
+		Check:   check,
+		Status:  status,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *checker) checkPluginInfo(ctx context.Context) {
+	rsp, err := csi.NewIdentityClient(c.conn).GetPluginInfo(ctx, &csi.GetPluginInfoRequest{})
+	if err != nil {
+		c.add("GetPluginInfo", StatusFail, "%v", err)
+		return
+	}
+	c.add("GetPluginInfo", StatusPass, "")
+	c.report.Driver = rsp.GetName()
+	if err := rpc.ValidateDriverName(rsp.GetName()); err != nil {
+		c.add("GetPluginInfo/name", StatusFail, "%v", err)
+	} else {
+		c.add("GetPluginInfo/name", StatusPass, "")
+	}
+	if rsp.GetVendorVersion() == "" {
+		c.add("GetPluginInfo/vendor_version", StatusWarn, "vendor version is required by the CSI spec but empty")
+	} else {
+		c.add("GetPluginInfo/vendor_version", StatusPass, "")
+	}
+}
+
+func (c *checker) checkProbe(ctx context.Context) {
+	ready, err := rpc.Probe(ctx, c.conn)
+	switch {
+	case status.Code(err) == codes.FailedPrecondition:
+		c.add("Probe", StatusWarn, "driver reports a failed precondition: %v", err)
+	case err != nil:
+		c.add("Probe", StatusFail, "%v", err)
+	case !ready:
+		c.add("Probe", StatusWarn, "driver is not ready yet")
+	default:
+		c.add("Probe", StatusPass, "")
+	}
+}
+
+func (c *checker) checkPluginCapabilities(ctx context.Context) rpc.PluginCapabilitySet {
+	caps, err := rpc.GetPluginCapabilities(ctx, c.conn)
+	if err != nil {
+		c.add("GetPluginCapabilities", StatusFail, "%v", err)
+		return rpc.PluginCapabilitySet{}
+	}
+	c.add("GetPluginCapabilities", StatusPass, "%s", caps)
+	checkKnown(c, "GetPluginCapabilities/known", caps)
+	return caps
+}
+
+func (c *checker) checkControllerCapabilities(ctx context.Context, pluginCaps rpc.PluginCapabilitySet) {
+	if !pluginCaps[csi.PluginCapability_Service_CONTROLLER_SERVICE] {
+		c.add("ControllerGetCapabilities", StatusSkip, "CONTROLLER_SERVICE not advertised")
+		return
+	}
+	caps, err := rpc.GetControllerCapabilities(ctx, c.conn)
+	if err != nil {
+		c.add("ControllerGetCapabilities", StatusFail, "%v", err)
+		return
+	}
+	c.add("ControllerGetCapabilities", StatusPass, "%s", caps)
+	checkKnown(c, "ControllerGetCapabilities/known", caps)
+	checkDependencies(c, "ControllerGetCapabilities", caps, controllerDependencies)
+}
+
+func (c *checker) checkGroupControllerCapabilities(ctx context.Context, pluginCaps rpc.PluginCapabilitySet) {
+	if !pluginCaps[csi.PluginCapability_Service_GROUP_CONTROLLER_SERVICE] {
+		c.add("GroupControllerGetCapabilities", StatusSkip, "GROUP_CONTROLLER_SERVICE not advertised")
+		return
+	}
+	caps, err := rpc.GetGroupControllerCapabilities(ctx, c.conn)
+	if err != nil {
+		c.add("GroupControllerGetCapabilities", StatusFail, "%v", err)
+		return
+	}
+	c.add("GroupControllerGetCapabilities", StatusPass, "%s", caps)
+	checkKnown(c, "GroupControllerGetCapabilities/known", caps)
+}
+
+func (c *checker) checkNodeCapabilities(ctx context.Context) {
+	caps, err := rpc.GetNodeCapabilities(ctx, c.conn)
+	switch {
+	case status.Code(err) == codes.Unimplemented:
+		// Controller-only deployments don't need to serve the node service.
+		c.add("NodeGetCapabilities", StatusSkip, "node service not implemented")
+		return
+	case err != nil:
+		c.add("NodeGetCapabilities", StatusFail, "%v", err)
+		return
+	}
+	c.add("NodeGetCapabilities", StatusPass, "%s", caps)
+	checkKnown(c, "NodeGetCapabilities/known", caps)
+	checkDependencies(c, "NodeGetCapabilities", caps, nodeDependencies)
+}
+
+// capability is implemented by the enums of the capability sets.
+type capability interface {
+	~int32
+	String() string
+	Descriptor() protoreflect.EnumDescriptor
+}
+
+// dependency describes a capability which is only meaningful if some
+// other capabilities are also advertised. Each entry in requires is a
+// group of alternatives: at least one capability of every group must
+// be advertised.
+type dependency[E capability] struct {
+	capability E
+	requires   [][]E
+}
+
+var controllerDependencies = []dependency[csi.ControllerServiceCapability_RPC_Type]{
+	{csi.ControllerServiceCapability_RPC_LIST_VOLUMES_PUBLISHED_NODES, [][]csi.ControllerServiceCapability_RPC_Type{
+		{csi.ControllerServiceCapability_RPC_PUBLISH_UNPUBLISH_VOLUME},
+		{csi.ControllerServiceCapability_RPC_LIST_VOLUMES, csi.ControllerServiceCapability_RPC_GET_VOLUME},
+	}},
+	{csi.ControllerServiceCapability_RPC_CLONE_VOLUME, [][]csi.ControllerServiceCapability_RPC_Type{
+		{csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME},
+	}},
+	{csi.ControllerServiceCapability_RPC_PUBLISH_READONLY, [][]csi.ControllerServiceCapability_RPC_Type{
+		{csi.ControllerServiceCapability_RPC_PUBLISH_UNPUBLISH_VOLUME},
+	}},
+	{csi.ControllerServiceCapability_RPC_VOLUME_CONDITION, [][]csi.ControllerServiceCapability_RPC_Type{
+		{csi.ControllerServiceCapability_RPC_LIST_VOLUMES, csi.ControllerServiceCapability_RPC_GET_VOLUME},
+	}},
+}
+
+var nodeDependencies = []dependency[csi.NodeServiceCapability_RPC_Type]{
+	{csi.NodeServiceCapability_RPC_VOLUME_CONDITION, [][]csi.NodeServiceCapability_RPC_Type{
+		{csi.NodeServiceCapability_RPC_GET_VOLUME_STATS},
+	}},
+}
+
+// checkDependencies adds one result for each advertised capability
+// that has dependencies.
+func checkDependencies[E capability](c *checker, prefix string, caps map[E]bool, dependencies []dependency[E]) {
+	for _, dep := range dependencies {
+		if !caps[dep.capability] {
+			continue
+		}
+		check := prefix + "/" + dep.capability.String()
+		var missing []string
+		for _, group := range dep.requires {
+			var names []string
+			found := false
+			for _, required := range group {
+				names = append(names, required.String())
+				found = found || caps[required]
+			}
+			if !found {
+				missing = append(missing, strings.Join(names, " or "))
+			}
+		}
+		if len(missing) == 0 {
+			c.add(check, StatusPass, "")
+		} else {
+			c.add(check, StatusFail, "%s advertised without %s", dep.capability, strings.Join(missing, " and without "))
+		}
+	}
+}
+
+// checkKnown warns about UNKNOWN and about capabilities which are not
+// defined in the CSI spec used by this package. They may come from a
+// more recent spec, so this is not a failure.
+func checkKnown[E capability](c *checker, check string, caps map[E]bool) {
+	var unknown []string
+	for capability, enabled := range caps {
+		if enabled && (capability == 0 || capability.Descriptor().Values().ByNumber(protoreflect.EnumNumber(capability)) == nil) {
+			unknown = append(unknown, capability.String())
+		}
+	}
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		c.add(check, StatusWarn, "unknown capabilities: %s", strings.Join(unknown, ", "))
+	} else {
+		c.add(check, StatusPass, "")
+	}
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conformance
+
+import (
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubernetes-csi/csi-lib-utils/connection"
+	"github.com/kubernetes-csi/csi-lib-utils/rpc/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"k8s.io/klog/v2/ktesting"
+)
+
+func TestCheck(t *testing.T) {
+	testcases := map[string]struct {
+		options      []fake.Option
+		script       func(d *fake.Driver)
+		expect       string
+		expectFailed bool
+	}{
+		"good": {
+			options: []fake.Option{
+				fake.WithName("good.csi.k8s.io", "1.0.0"),
+				fake.WithPluginCapabilities(csi.PluginCapability_Service_CONTROLLER_SERVICE),
+				fake.WithControllerCapabilities(
+					csi.ControllerServiceCapability_RPC_LIST_VOLUMES,
+					csi.ControllerServiceCapability_RPC_LIST_VOLUMES_PUBLISHED_NODES,
+					csi.ControllerServiceCapability_RPC_PUBLISH_UNPUBLISH_VOLUME,
+				),
+				fake.WithNodeCapabilities(csi.NodeServiceCapability_RPC_GET_VOLUME_STATS, csi.NodeServiceCapability_RPC_VOLUME_CONDITION),
+			},
+			expect: `pass: GetPluginInfo
+pass: GetPluginInfo/name
+pass: GetPluginInfo/vendor_version
+pass: Probe
+pass: GetPluginCapabilities: CONTROLLER_SERVICE
+pass: GetPluginCapabilities/known
+pass: ControllerGetCapabilities: LIST_VOLUMES,LIST_VOLUMES_PUBLISHED_NODES,PUBLISH_UNPUBLISH_VOLUME
+pass: ControllerGetCapabilities/known
+pass: ControllerGetCapabilities/LIST_VOLUMES_PUBLISHED_NODES
+skip: GroupControllerGetCapabilities: GROUP_CONTROLLER_SERVICE not advertised
+pass: NodeGetCapabilities: GET_VOLUME_STATS,VOLUME_CONDITION
+pass: NodeGetCapabilities/known
+pass: NodeGetCapabilities/VOLUME_CONDITION
+`,
+		},
+		"bad": {
+			options: []fake.Option{
+				fake.WithName("-bad-", ""),
+				fake.WithPluginCapabilities(csi.PluginCapability_Service_CONTROLLER_SERVICE, csi.PluginCapability_Service_GROUP_CONTROLLER_SERVICE),
+				fake.WithControllerCapabilities(
+					csi.ControllerServiceCapability_RPC_LIST_VOLUMES_PUBLISHED_NODES,
+					csi.ControllerServiceCapability_RPC_VOLUME_CONDITION,
+					1000,
+				),
+			},
+			script: func(d *fake.Driver) {
+				d.SetResponse(csi.Identity_Probe_FullMethodName, &csi.ProbeResponse{Ready: wrapperspb.Bool(false)})
+				d.SetError(csi.GroupController_GroupControllerGetCapabilities_FullMethodName, status.Error(codes.Unimplemented, "not implemented"))
+				d.SetError(csi.Node_NodeGetCapabilities_FullMethodName, status.Error(codes.Unimplemented, "not implemented"))
+			},
+			expect: `pass: GetPluginInfo
+fail: GetPluginInfo/name: driver name "-bad-" must begin and end with an alphanumeric character and contain only dashes, dots and alphanumerics in between
+warn: GetPluginInfo/vendor_version: vendor version is required by the CSI spec but empty
+warn: Probe: driver is not ready yet
+pass: GetPluginCapabilities: CONTROLLER_SERVICE,GROUP_CONTROLLER_SERVICE
+pass: GetPluginCapabilities/known
+pass: ControllerGetCapabilities: 1000,LIST_VOLUMES_PUBLISHED_NODES,VOLUME_CONDITION
+warn: ControllerGetCapabilities/known: unknown capabilities: 1000
+fail: ControllerGetCapabilities/LIST_VOLUMES_PUBLISHED_NODES: LIST_VOLUMES_PUBLISHED_NODES advertised without PUBLISH_UNPUBLISH_VOLUME and without LIST_VOLUMES or GET_VOLUME
+fail: ControllerGetCapabilities/VOLUME_CONDITION: VOLUME_CONDITION advertised without LIST_VOLUMES or GET_VOLUME
+fail: GroupControllerGetCapabilities: rpc error: code = Unimplemented desc = not implemented
+skip: NodeGetCapabilities: node service not implemented
+`,
+			expectFailed: true,
+		},
+		"published nodes without publish": {
+			options: []fake.Option{
+				fake.WithName("published.csi.k8s.io", "1.0.0"),
+				fake.WithPluginCapabilities(csi.PluginCapability_Service_CONTROLLER_SERVICE),
+				fake.WithControllerCapabilities(
+					csi.ControllerServiceCapability_RPC_GET_VOLUME,
+					csi.ControllerServiceCapability_RPC_LIST_VOLUMES_PUBLISHED_NODES,
+				),
+			},
+			expect: `pass: GetPluginInfo
+pass: GetPluginInfo/name
+pass: GetPluginInfo/vendor_version
+pass: Probe
+pass: GetPluginCapabilities: CONTROLLER_SERVICE
+pass: GetPluginCapabilities/known
+pass: ControllerGetCapabilities: GET_VOLUME,LIST_VOLUMES_PUBLISHED_NODES
+pass: ControllerGetCapabilities/known
+fail: ControllerGetCapabilities/LIST_VOLUMES_PUBLISHED_NODES: LIST_VOLUMES_PUBLISHED_NODES advertised without PUBLISH_UNPUBLISH_VOLUME
+skip: GroupControllerGetCapabilities: GROUP_CONTROLLER_SERVICE not advertised
+pass: NodeGetCapabilities
+pass: NodeGetCapabilities/known
+`,
+			expectFailed: true,
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			driver := fake.Start(t, tc.options...)
+			if tc.script != nil {
+				tc.script(driver)
+			}
+			logger, ctx := ktesting.NewTestContext(t)
+			conn, err := connection.Connect(ctx, driver.Address(), nil)
+			require.NoError(t, err, "connect")
+			defer conn.Close()
+
+			report := Check(ctx, conn)
+			report.Log(logger)
+			assert.Equal(t, tc.expect, report.String())
+			assert.Equal(t, tc.expectFailed, report.Failed(), "Failed")
+		})
+	}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rpc
+
+import (
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Sources for the classification below:
+// https://github.com/grpc/grpc/blob/master/doc/statuscodes.md
+// https://github.com/container-storage-interface/spec/blob/master/spec.md
+//
+// Errors which are not gRPC errors are handled like the metrics package
+// does: the call must have failed before the gRPC method was invoked,
+// so nothing is known about the state of the operation.
+
+// errorClass describes how an error has to be handled by the caller.
+type errorClass struct {
+	final      bool
+	transient  bool
+	inProgress bool
+}
+
+var (
+	// classFinal is for errors which need some change before a retry
+	// makes sense.
+	classFinal = errorClass{final: true}
+	// classFinalTransient is for failed calls which may succeed when
+	// retried without changes.
+	classFinalTransient = errorClass{final: true, transient: true}
+	// classPending is for calls which may still be running.
+	classPending = errorClass{transient: true}
+	// classInProgress is for calls where the driver reported that an
+	// operation for the same volume or snapshot is pending.
+	classInProgress = errorClass{transient: true, inProgress: true}
+)
+
+// defaultErrorClasses apply to all methods, unless overridden in
+// methodErrorClasses. Codes which are not listed are final and not
+// transient.
+var defaultErrorClasses = map[codes.Code]errorClass{
+	codes.OK:                classFinal,
+	codes.Canceled:          classPending,
+	codes.DeadlineExceeded:  classPending,
+	codes.Unavailable:       classPending,
+	codes.ResourceExhausted: classPending,
+	codes.Aborted:           classInProgress,
+	codes.Internal:          classFinalTransient,
+	codes.Unknown:           classFinalTransient,
+}
+
+// methodErrorClasses follow the error tables of the individual methods
+// in the CSI spec where they give a code a different meaning than the
+// gRPC default.
+var methodErrorClasses = map[string]map[codes.Code]errorClass{
+	csi.Controller_CreateVolume_FullMethodName: {
+		// Unable to provision in accessible_topology: the volume may
+		// get created once capacity becomes available.
+		codes.ResourceExhausted: classPending,
+	},
+	csi.Controller_DeleteVolume_FullMethodName: {
+		// Volume in use: retry with exponential backoff.
+		codes.FailedPrecondition: classFinalTransient,
+	},
+	csi.Controller_ControllerPublishVolume_FullMethodName: {
+		// Max volumes attached: some other volume has to be
+		// unpublished from the node first.
+		codes.ResourceExhausted: classFinal,
+	},
+	csi.Controller_ControllerExpandVolume_FullMethodName: {
+		// Volume in use: retry with exponential backoff.
+		codes.FailedPrecondition: classFinalTransient,
+	},
+	csi.Controller_CreateSnapshot_FullMethodName: {
+		// Not enough space to create snapshot: the call failed.
+		codes.ResourceExhausted: classFinal,
+	},
+	csi.Controller_DeleteSnapshot_FullMethodName: {
+		// Snapshot in use: retry with exponential backoff.
+		codes.FailedPrecondition: classFinalTransient,
+	},
+}
+
+// classifyError looks up the class of an error returned by the method,
+// which is the full gRPC method name like
+// csi.Controller_CreateVolume_FullMethodName.
+func classifyError(method string, err error) errorClass {
+	st, ok := status.FromError(err)
+	if !ok {
+		return classPending
+	}
+	if class, ok := methodErrorClasses[method][st.Code()]; ok {
+		return class
+	}
+	if class, ok := defaultErrorClasses[st.Code()]; ok {
+		return class
+	}
+	return classFinal
+}
+
+// IsFinalError returns true if the operation is known to no longer be
+// running in the driver, either because it completed or because it
+// failed. This is the case for a nil error and for most gRPC codes.
+//
+// It returns false if the operation may still be in progress and the
+// caller must call again to learn the outcome, for example before
+// giving up on a volume which might have been created:
+//   - Canceled and DeadlineExceeded: the caller stopped waiting.
+//   - Unavailable: the connection broke or the driver is shutting down.
+//   - ResourceExhausted: the driver is temporarily out of resources.
+//     ControllerPublishVolume and CreateSnapshot use it for a final
+//     failure instead.
+//   - Aborted: CSI uses it for "operation pending for volume".
+//   - errors which are not gRPC errors.
+//
+// The method is the full gRPC method name of the call which returned
+// the error, for example csi.Controller_CreateVolume_FullMethodName.
+// Unknown methods get the default classification.
+func IsFinalError(method string, err error) bool {
+	return classifyError(method, err).final
+}
+
+// IsTransient returns true if retrying the same call without changes
+// may succeed. This is the case for all errors where IsFinalError
+// returns false and in addition for Internal and Unknown, which
+// indicate a failure in the driver rather than a problem with the
+// request. Codes like InvalidArgument, NotFound, AlreadyExists,
+// FailedPrecondition, OutOfRange or Unimplemented need some change
+// before a retry makes sense. The exception is FailedPrecondition for
+// "volume in use" or "snapshot in use" of DeleteVolume,
+// ControllerExpandVolume and DeleteSnapshot, which the CSI spec says
+// to retry with exponential backoff.
+func IsTransient(method string, err error) bool {
+	return classifyError(method, err).transient
+}
+
+// IsInProgress returns true if the driver reported that an operation
+// for the same volume or snapshot is already pending. The CSI spec
+// defines the Aborted code for that in all methods. Callers should
+// retry with exponential backoff.
+func IsInProgress(method string, err error) bool {
+	return classifyError(method, err).inProgress
+}
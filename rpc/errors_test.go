@@ -0,0 +1,147 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorClassification(t *testing.T) {
+	testcases := map[string]struct {
+		method           string
+		err              error
+		expectFinal      bool
+		expectTransient  bool
+		expectInProgress bool
+	}{
+		"nil": {
+			expectFinal: true,
+		},
+		"non-gRPC": {
+			err:             errors.New("connection refused"),
+			expectTransient: true,
+		},
+		"aborted": {
+			err:              status.Error(codes.Aborted, "operation pending"),
+			expectTransient:  true,
+			expectInProgress: true,
+		},
+		"wrapped aborted": {
+			err:              fmt.Errorf("create volume: %w", status.Error(codes.Aborted, "operation pending")),
+			expectTransient:  true,
+			expectInProgress: true,
+		},
+		"deadline exceeded": {
+			err:             status.Error(codes.DeadlineExceeded, "timeout"),
+			expectTransient: true,
+		},
+		"unavailable": {
+			err:             status.Error(codes.Unavailable, "shutting down"),
+			expectTransient: true,
+		},
+		"resource exhausted": {
+			err:             status.Error(codes.ResourceExhausted, "busy"),
+			expectTransient: true,
+		},
+		"internal": {
+			err:             status.Error(codes.Internal, "oops"),
+			expectFinal:     true,
+			expectTransient: true,
+		},
+		"invalid argument": {
+			err:         status.Error(codes.InvalidArgument, "bad name"),
+			expectFinal: true,
+		},
+		"failed precondition": {
+			err:         status.Error(codes.FailedPrecondition, "volume in use"),
+			expectFinal: true,
+		},
+		"unknown method": {
+			method:          "/csi.v1.Controller/NoSuchMethod",
+			err:             status.Error(codes.ResourceExhausted, "busy"),
+			expectTransient: true,
+		},
+		"create volume resource exhausted": {
+			method:          csi.Controller_CreateVolume_FullMethodName,
+			err:             status.Error(codes.ResourceExhausted, "no capacity in topology"),
+			expectTransient: true,
+		},
+		"create volume aborted": {
+			method:           csi.Controller_CreateVolume_FullMethodName,
+			err:              status.Error(codes.Aborted, "operation pending"),
+			expectTransient:  true,
+			expectInProgress: true,
+		},
+		"delete volume failed precondition": {
+			method:          csi.Controller_DeleteVolume_FullMethodName,
+			err:             status.Error(codes.FailedPrecondition, "volume in use"),
+			expectFinal:     true,
+			expectTransient: true,
+		},
+		"publish resource exhausted": {
+			method:      csi.Controller_ControllerPublishVolume_FullMethodName,
+			err:         status.Error(codes.ResourceExhausted, "max volumes attached"),
+			expectFinal: true,
+		},
+		"publish aborted": {
+			method:           csi.Controller_ControllerPublishVolume_FullMethodName,
+			err:              status.Error(codes.Aborted, "operation pending"),
+			expectTransient:  true,
+			expectInProgress: true,
+		},
+		"publish failed precondition": {
+			method:      csi.Controller_ControllerPublishVolume_FullMethodName,
+			err:         status.Error(codes.FailedPrecondition, "published to another node"),
+			expectFinal: true,
+		},
+		"create snapshot resource exhausted": {
+			method:      csi.Controller_CreateSnapshot_FullMethodName,
+			err:         status.Error(codes.ResourceExhausted, "not enough space"),
+			expectFinal: true,
+		},
+		"create snapshot deadline exceeded": {
+			method:          csi.Controller_CreateSnapshot_FullMethodName,
+			err:             status.Error(codes.DeadlineExceeded, "timeout"),
+			expectTransient: true,
+		},
+		"delete snapshot failed precondition": {
+			method:          csi.Controller_DeleteSnapshot_FullMethodName,
+			err:             status.Error(codes.FailedPrecondition, "snapshot in use"),
+			expectFinal:     true,
+			expectTransient: true,
+		},
+		"unsupported": {
+			err:         &UnsupportedError{Method: csi.Controller_CreateVolume_FullMethodName, Capability: csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME},
+			expectFinal: true,
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expectFinal, IsFinalError(tc.method, tc.err), "IsFinalError")
+			assert.Equal(t, tc.expectTransient, IsTransient(tc.method, tc.err), "IsTransient")
+			assert.Equal(t, tc.expectInProgress, IsInProgress(tc.method, tc.err), "IsInProgress")
+		})
+	}
+}
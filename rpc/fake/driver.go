@@ -0,0 +1,371 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fake provides an in-process CSI driver for unit tests.
+//
+// The driver serves the Identity, Controller, GroupController and Node
+// services on a Unix domain socket, so connection.Connect can be used
+// as with a real driver. Capabilities, plugin info and node info are
+// configured with options. Every other method returns Unimplemented
+// unless a handler, response or error was set for it. All calls are
+// recorded.
+package fake
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	// DefaultName is the driver name returned by GetPluginInfo unless
+	// WithName is used.
+	DefaultName = "fake.csi.k8s.io"
+	// DefaultNodeID is the node ID returned by NodeGetInfo unless
+	// WithNodeInfo is used.
+	DefaultNodeID = "fake-node"
+)
+
+// Handler replaces the implementation of a method. The request has the
+// type defined for the method, for example *csi.CreateVolumeRequest,
+// and the response must have the corresponding response type.
+type Handler func(ctx context.Context, req proto.Message) (proto.Message, error)
+
+// Call is one recorded invocation of a method.
+type Call struct {
+	// Method is the full gRPC method name, for example
+	// csi.Controller_CreateVolume_FullMethodName.
+	Method   string
+	Request  proto.Message
+	Response proto.Message
+	Err      error
+}
+
+// Option configures a Driver.
+type Option func(*Driver)
+
+// WithName sets the name and vendor version returned by GetPluginInfo.
+func WithName(name, vendorVersion string) Option {
+	return func(d *Driver) {
+		d.pluginInfo = &csi.GetPluginInfoResponse{Name: name, VendorVersion: vendorVersion}
+	}
+}
+
+// WithPluginCapabilities sets the service capabilities returned by GetPluginCapabilities.
+func WithPluginCapabilities(capabilities ...csi.PluginCapability_Service_Type) Option {
+	return func(d *Driver) {
+		d.pluginCapabilities = capabilities
+	}
+}
+
+// WithControllerCapabilities sets the capabilities returned by ControllerGetCapabilities.
+func WithControllerCapabilities(capabilities ...csi.ControllerServiceCapability_RPC_Type) Option {
+	return func(d *Driver) {
+		d.controllerCapabilities = capabilities
+	}
+}
+
+// WithGroupControllerCapabilities sets the capabilities returned by GroupControllerGetCapabilities.
+func WithGroupControllerCapabilities(capabilities ...csi.GroupControllerServiceCapability_RPC_Type) Option {
+	return func(d *Driver) {
+		d.groupControllerCapabilities = capabilities
+	}
+}
+
+// WithNodeCapabilities sets the capabilities returned by NodeGetCapabilities.
+func WithNodeCapabilities(capabilities ...csi.NodeServiceCapability_RPC_Type) Option {
+	return func(d *Driver) {
+		d.nodeCapabilities = capabilities
+	}
+}
+
+// WithNodeInfo sets the response of NodeGetInfo.
+func WithNodeInfo(info *csi.NodeGetInfoResponse) Option {
+	return func(d *Driver) {
+		d.nodeInfo = info
+	}
+}
+
+// Driver is a scriptable CSI driver. It is safe to change its behavior
+// while calls are in flight.
+type Driver struct {
+	pluginInfo                  *csi.GetPluginInfoResponse
+	pluginCapabilities          []csi.PluginCapability_Service_Type
+	controllerCapabilities      []csi.ControllerServiceCapability_RPC_Type
+	groupControllerCapabilities []csi.GroupControllerServiceCapability_RPC_Type
+	nodeCapabilities            []csi.NodeServiceCapability_RPC_Type
+	nodeInfo                    *csi.NodeGetInfoResponse
+
+	address string
+	server  *grpc.Server
+	wg      sync.WaitGroup
+
+	mutex    sync.Mutex
+	handlers map[string]Handler
+	latency  map[string]time.Duration
+	calls    []Call
+}
+
+// Start creates a driver and serves it until the test ends. By default,
+// the driver has no capabilities and Probe reports that it is ready.
+func Start(tb testing.TB, options ...Option) *Driver {
+	tb.Helper()
+	d := &Driver{
+		pluginInfo: &csi.GetPluginInfoResponse{Name: DefaultName},
+		nodeInfo:   &csi.NodeGetInfoResponse{NodeId: DefaultNodeID},
+		handlers:   map[string]Handler{},
+		latency:    map[string]time.Duration{},
+	}
+	for _, option := range options {
+		option(d)
+	}
+
+	// t.TempDir would be more convenient, but can exceed the maximum
+	// length of a Unix domain socket path.
+	dir, err := os.MkdirTemp("", "csi-fake")
+	if err != nil {
+		tb.Fatalf("create directory for socket: %v", err)
+	}
+	tb.Cleanup(func() {
+		_ = os.RemoveAll(dir)
+	})
+	d.address = filepath.Join(dir, "csi.sock")
+	listener, err := net.Listen("unix", d.address)
+	if err != nil {
+		tb.Fatalf("listen on %s: %v", d.address, err)
+	}
+
+	d.server = grpc.NewServer(grpc.UnaryInterceptor(d.intercept))
+	csi.RegisterIdentityServer(d.server, &identityServer{d: d})
+	csi.RegisterControllerServer(d.server, &controllerServer{d: d})
+	csi.RegisterGroupControllerServer(d.server, &groupControllerServer{d: d})
+	csi.RegisterNodeServer(d.server, &nodeServer{d: d})
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		_ = d.server.Serve(listener)
+	}()
+	tb.Cleanup(d.Stop)
+	return d
+}
+
+// Address returns the path of the Unix domain socket.
+func (d *Driver) Address() string {
+	return d.address
+}
+
+// Stop shuts down the server immediately. It is called automatically
+// when the test ends and may be called more than once.
+func (d *Driver) Stop() {
+	d.server.Stop()
+	d.wg.Wait()
+}
+
+// SetHandler replaces the implementation of a method, identified by its
+// full gRPC method name. A nil handler restores the default behavior.
+func (d *Driver) SetHandler(method string, handler Handler) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if handler == nil {
+		delete(d.handlers, method)
+		return
+	}
+	d.handlers[method] = handler
+}
+
+// SetResponse makes a method return the response. The response is
+// returned unchanged for every call.
+func (d *Driver) SetResponse(method string, response proto.Message) {
+	d.SetHandler(method, func(context.Context, proto.Message) (proto.Message, error) {
+		return response, nil
+	})
+}
+
+// SetError makes a method fail with the error, typically created with
+// status.Error.
+func (d *Driver) SetError(method string, err error) {
+	d.SetHandler(method, func(context.Context, proto.Message) (proto.Message, error) {
+		return nil, err
+	})
+}
+
+// SetLatency delays each call of the method. When the call's context
+// ends first, the call fails with the corresponding gRPC status.
+// Zero disables the delay.
+func (d *Driver) SetLatency(method string, latency time.Duration) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if latency == 0 {
+		delete(d.latency, method)
+		return
+	}
+	d.latency[method] = latency
+}
+
+// Calls returns all calls received so far, in the order in which
+// they completed.
+func (d *Driver) Calls() []Call {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return append([]Call(nil), d.calls...)
+}
+
+// CallsTo returns the calls of one method.
+func (d *Driver) CallsTo(method string) []Call {
+	var calls []Call
+	for _, call := range d.Calls() {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// ResetCalls discards the recorded calls.
+func (d *Driver) ResetCalls() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.calls = nil
+}
+
+// intercept implements latency, scripted handlers and call recording
+// for all methods.
+func (d *Driver) intercept(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	d.mutex.Lock()
+	latency := d.latency[info.FullMethod]
+	scripted := d.handlers[info.FullMethod]
+	d.mutex.Unlock()
+
+	var rsp any
+	var err error
+	if latency > 0 {
+		select {
+		case <-ctx.Done():
+			err = status.FromContextError(ctx.Err()).Err()
+		case <-time.After(latency):
+		}
+	}
+	if err == nil {
+		if scripted != nil {
+			rsp, err = scripted(ctx, req.(proto.Message))
+		} else {
+			rsp, err = handler(ctx, req)
+		}
+	}
+
+	call := Call{
+		Method:  info.FullMethod,
+		Request: req.(proto.Message),
+		Err:     err,
+	}
+	if rsp, ok := rsp.(proto.Message); ok && err == nil {
+		call.Response = rsp
+	}
+	d.mutex.Lock()
+	d.calls = append(d.calls, call)
+	d.mutex.Unlock()
+	return rsp, err
+}
+
+type identityServer struct {
+	csi.UnimplementedIdentityServer
+	d *Driver
+}
+
+func (s *identityServer) GetPluginInfo(context.Context, *csi.GetPluginInfoRequest) (*csi.GetPluginInfoResponse, error) {
+	return s.d.pluginInfo, nil
+}
+
+func (s *identityServer) GetPluginCapabilities(context.Context, *csi.GetPluginCapabilitiesRequest) (*csi.GetPluginCapabilitiesResponse, error) {
+	rsp := &csi.GetPluginCapabilitiesResponse{}
+	for _, capability := range s.d.pluginCapabilities {
+		rsp.Capabilities = append(rsp.Capabilities, &csi.PluginCapability{
+			Type: &csi.PluginCapability_Service_{
+				Service: &csi.PluginCapability_Service{Type: capability},
+			},
+		})
+	}
+	return rsp, nil
+}
+
+func (s *identityServer) Probe(context.Context, *csi.ProbeRequest) (*csi.ProbeResponse, error) {
+	return &csi.ProbeResponse{Ready: wrapperspb.Bool(true)}, nil
+}
+
+type controllerServer struct {
+	csi.UnimplementedControllerServer
+	d *Driver
+}
+
+func (s *controllerServer) ControllerGetCapabilities(context.Context, *csi.ControllerGetCapabilitiesRequest) (*csi.ControllerGetCapabilitiesResponse, error) {
+	rsp := &csi.ControllerGetCapabilitiesResponse{}
+	for _, capability := range s.d.controllerCapabilities {
+		rsp.Capabilities = append(rsp.Capabilities, &csi.ControllerServiceCapability{
+			Type: &csi.ControllerServiceCapability_Rpc{
+				Rpc: &csi.ControllerServiceCapability_RPC{Type: capability},
+			},
+		})
+	}
+	return rsp, nil
+}
+
+type groupControllerServer struct {
+	csi.UnimplementedGroupControllerServer
+	d *Driver
+}
+
+func (s *groupControllerServer) GroupControllerGetCapabilities(context.Context, *csi.GroupControllerGetCapabilitiesRequest) (*csi.GroupControllerGetCapabilitiesResponse, error) {
+	rsp := &csi.GroupControllerGetCapabilitiesResponse{}
+	for _, capability := range s.d.groupControllerCapabilities {
+		rsp.Capabilities = append(rsp.Capabilities, &csi.GroupControllerServiceCapability{
+			Type: &csi.GroupControllerServiceCapability_Rpc{
+				Rpc: &csi.GroupControllerServiceCapability_RPC{Type: capability},
+			},
+		})
+	}
+	return rsp, nil
+}
+
+type nodeServer struct {
+	csi.UnimplementedNodeServer
+	d *Driver
+}
+
+func (s *nodeServer) NodeGetCapabilities(context.Context, *csi.NodeGetCapabilitiesRequest) (*csi.NodeGetCapabilitiesResponse, error) {
+	rsp := &csi.NodeGetCapabilitiesResponse{}
+	for _, capability := range s.d.nodeCapabilities {
+		rsp.Capabilities = append(rsp.Capabilities, &csi.NodeServiceCapability{
+			Type: &csi.NodeServiceCapability_Rpc{
+				Rpc: &csi.NodeServiceCapability_RPC{Type: capability},
+			},
+		})
+	}
+	return rsp, nil
+}
+
+func (s *nodeServer) NodeGetInfo(context.Context, *csi.NodeGetInfoRequest) (*csi.NodeGetInfoResponse, error) {
+	return s.d.nodeInfo, nil
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubernetes-csi/csi-lib-utils/connection"
+	"github.com/kubernetes-csi/csi-lib-utils/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"k8s.io/klog/v2/ktesting"
+)
+
+func TestDriver(t *testing.T) {
+	driver := Start(t,
+		WithName("test.csi.k8s.io", "1.0.0"),
+		WithPluginCapabilities(csi.PluginCapability_Service_CONTROLLER_SERVICE),
+		WithControllerCapabilities(csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME),
+		WithNodeCapabilities(csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME),
+	)
+	_, ctx := ktesting.NewTestContext(t)
+	conn, err := connection.Connect(ctx, driver.Address(), nil)
+	require.NoError(t, err, "connect")
+	defer conn.Close()
+
+	client, err := rpc.NewClient(ctx, conn)
+	require.NoError(t, err, "discover capabilities")
+	assert.Equal(t, "CREATE_DELETE_VOLUME", client.ControllerCapabilities().String())
+	assert.Equal(t, "STAGE_UNSTAGE_VOLUME", client.NodeCapabilities().String())
+	name, err := rpc.GetDriverName(ctx, conn)
+	require.NoError(t, err)
+	assert.Equal(t, "test.csi.k8s.io", name)
+	ready, err := rpc.Probe(ctx, conn)
+	require.NoError(t, err)
+	assert.True(t, ready, "ready")
+	info, err := rpc.GetNodeInfo(ctx, conn)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultNodeID, info.NodeID)
+
+	controller := csi.NewControllerClient(conn)
+	_, err = controller.CreateVolume(ctx, &csi.CreateVolumeRequest{Name: "unscripted"})
+	assert.Equal(t, codes.Unimplemented, status.Code(err), "unscripted method")
+
+	volume := &csi.CreateVolumeResponse{Volume: &csi.Volume{VolumeId: "vol-1"}}
+	driver.SetResponse(csi.Controller_CreateVolume_FullMethodName, volume)
+	rsp, err := controller.CreateVolume(ctx, &csi.CreateVolumeRequest{Name: "scripted"})
+	require.NoError(t, err)
+	assert.Equal(t, "vol-1", rsp.GetVolume().GetVolumeId())
+
+	driver.SetError(csi.Controller_CreateVolume_FullMethodName, status.Error(codes.ResourceExhausted, "full"))
+	_, err = controller.CreateVolume(ctx, &csi.CreateVolumeRequest{Name: "failed"})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "scripted error")
+
+	driver.SetHandler(csi.Controller_CreateVolume_FullMethodName, nil)
+	driver.SetLatency(csi.Controller_CreateVolume_FullMethodName, time.Hour)
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = controller.CreateVolume(timeoutCtx, &csi.CreateVolumeRequest{Name: "slow"})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err), "latency")
+
+	assert.Eventually(t, func() bool {
+		return len(driver.CallsTo(csi.Controller_CreateVolume_FullMethodName)) == 4
+	}, 10*time.Second, 10*time.Millisecond, "all CreateVolume calls recorded")
+	calls := driver.CallsTo(csi.Controller_CreateVolume_FullMethodName)
+	for i, name := range []string{"unscripted", "scripted", "failed", "slow"} {
+		assert.Equal(t, name, calls[i].Request.(*csi.CreateVolumeRequest).Name, "call #%d", i)
+	}
+	assert.True(t, proto.Equal(volume, calls[1].Response), "recorded response")
+	assert.Equal(t, codes.ResourceExhausted, status.Code(calls[2].Err), "recorded error")
+	assert.NotEmpty(t, driver.CallsTo(csi.Identity_GetPluginCapabilities_FullMethodName), "GetPluginCapabilities calls")
+
+	driver.ResetCalls()
+	assert.Empty(t, driver.Calls(), "calls after reset")
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrInvalidStartingToken is wrapped by the error returned by ListVolumes
+// and ListSnapshots when the driver rejects a starting_token that it
+// returned earlier as next_token, for example because the token expired
+// or the list of volumes changed. The listing must be restarted from
+// the beginning.
+var ErrInvalidStartingToken = errors.New("CSI driver rejected the starting token")
+
+// ErrTokenLoop is wrapped by the error returned by ListVolumes and
+// ListSnapshots when the driver returns a next_token that was already
+// used before. Continuing would never end.
+var ErrTokenLoop = errors.New("CSI driver returned a next token which was used before")
+
+// ListVolumes returns an iterator over all volumes. The volumes are
+// retrieved with as many ListVolumes calls as needed, each asking for
+// at most pageSize entries. Zero lets the driver pick the page size.
+//
+// When a call fails, the iterator yields the error once and stops.
+// The error wraps the gRPC error, plus ErrInvalidStartingToken or
+// ErrTokenLoop where applicable.
+func ListVolumes(ctx context.Context, client csi.ControllerClient, pageSize int32) iter.Seq2[*csi.ListVolumesResponse_Entry, error] {
+	return paginate(ctx, "ListVolumes", func(ctx context.Context, token string) ([]*csi.ListVolumesResponse_Entry, string, error) {
+		rsp, err := client.ListVolumes(ctx, &csi.ListVolumesRequest{
+			MaxEntries:    pageSize,
+			StartingToken: token,
+		})
+		return rsp.GetEntries(), rsp.GetNextToken(), err
+	})
+}
+
+// ListSnapshots returns an iterator over all snapshots which match the
+// filter. SourceVolumeId, SnapshotId and Secrets are copied from the
+// filter into each request, the filter may be nil. Paging and error
+// handling are the same as for ListVolumes.
+func ListSnapshots(ctx context.Context, client csi.ControllerClient, pageSize int32, filter *csi.ListSnapshotsRequest) iter.Seq2[*csi.ListSnapshotsResponse_Entry, error] {
+	return paginate(ctx, "ListSnapshots", func(ctx context.Context, token string) ([]*csi.ListSnapshotsResponse_Entry, string, error) {
+		req := &csi.ListSnapshotsRequest{}
+		if filter != nil {
+			req = proto.Clone(filter).(*csi.ListSnapshotsRequest)
+		}
+		req.MaxEntries = pageSize
+		req.StartingToken = token
+		rsp, err := client.ListSnapshots(ctx, req)
+		return rsp.GetEntries(), rsp.GetNextToken(), err
+	})
+}
+
+// paginate implements the iterator for one list method.
+func paginate[E any](ctx context.Context, method string, list func(ctx context.Context, token string) ([]E, string, error)) iter.Seq2[E, error] {
+	return func(yield func(E, error) bool) {
+		var zero E
+		var token string
+		seen := map[string]bool{}
+		for {
+			entries, nextToken, err := list(ctx, token)
+			if err != nil {
+				if token != "" && status.Code(err) == codes.Aborted {
+					err = fmt.Errorf("%s with starting token %q: %w: %w", method, token, ErrInvalidStartingToken, err)
+				} else {
+					err = fmt.Errorf("%s with starting token %q: %w", method, token, err)
+				}
+				yield(zero, err)
+				return
+			}
+			for _, entry := range entries {
+				if !yield(entry, nil) {
+					return
+				}
+			}
+			if nextToken == "" {
+				return
+			}
+			seen[token] = true
+			if seen[nextToken] {
+				yield(zero, fmt.Errorf("%s: %w: %q", method, ErrTokenLoop, nextToken))
+				return
+			}
+			token = nextToken
+		}
+	}
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// pagingControllerClient serves entries from a fixed list, using the
+// index of the next entry as token.
+type pagingControllerClient struct {
+	csi.ControllerClient
+
+	ids []string
+	// nextToken, if set, replaces the computed next token.
+	nextToken func(token string) string
+	// err, if set, is returned for that starting token.
+	err map[string]error
+
+	requests []*csi.ListSnapshotsRequest
+}
+
+func (c *pagingControllerClient) page(token string, maxEntries int32) ([]string, string, error) {
+	if err := c.err[token]; err != nil {
+		return nil, "", err
+	}
+	start := 0
+	if token != "" {
+		var err error
+		start, err = strconv.Atoi(token)
+		if err != nil || start > len(c.ids) {
+			return nil, "", status.Error(codes.Aborted, "invalid starting token")
+		}
+	}
+	end := len(c.ids)
+	if maxEntries > 0 && start+int(maxEntries) < end {
+		end = start + int(maxEntries)
+	}
+	nextToken := ""
+	if end < len(c.ids) {
+		nextToken = strconv.Itoa(end)
+	}
+	if c.nextToken != nil {
+		nextToken = c.nextToken(token)
+	}
+	return c.ids[start:end], nextToken, nil
+}
+
+func (c *pagingControllerClient) ListVolumes(ctx context.Context, req *csi.ListVolumesRequest, opts ...grpc.CallOption) (*csi.ListVolumesResponse, error) {
+	ids, nextToken, err := c.page(req.StartingToken, req.MaxEntries)
+	if err != nil {
+		return nil, err
+	}
+	rsp := &csi.ListVolumesResponse{NextToken: nextToken}
+	for _, id := range ids {
+		rsp.Entries = append(rsp.Entries, &csi.ListVolumesResponse_Entry{Volume: &csi.Volume{VolumeId: id}})
+	}
+	return rsp, nil
+}
+
+func (c *pagingControllerClient) ListSnapshots(ctx context.Context, req *csi.ListSnapshotsRequest, opts ...grpc.CallOption) (*csi.ListSnapshotsResponse, error) {
+	c.requests = append(c.requests, req)
+	ids, nextToken, err := c.page(req.StartingToken, req.MaxEntries)
+	if err != nil {
+		return nil, err
+	}
+	rsp := &csi.ListSnapshotsResponse{NextToken: nextToken}
+	for _, id := range ids {
+		rsp.Entries = append(rsp.Entries, &csi.ListSnapshotsResponse_Entry{Snapshot: &csi.Snapshot{SnapshotId: id}})
+	}
+	return rsp, nil
+}
+
+func TestListVolumes(t *testing.T) {
+	ids := []string{"a", "b", "c", "d", "e"}
+	testcases := map[string]struct {
+		client      *pagingControllerClient
+		pageSize    int32
+		expectIDs   []string
+		expectError error
+		expectCode  codes.Code
+	}{
+		"single page": {
+			client:    &pagingControllerClient{ids: ids},
+			expectIDs: ids,
+		},
+		"multiple pages": {
+			client:    &pagingControllerClient{ids: ids},
+			pageSize:  2,
+			expectIDs: ids,
+		},
+		"empty": {
+			client: &pagingControllerClient{},
+		},
+		"loop": {
+			client: &pagingControllerClient{
+				ids:       ids,
+				nextToken: func(token string) string { return map[string]string{"": "2", "2": "4", "4": "2"}[token] },
+			},
+			pageSize:    2,
+			expectIDs:   []string{"a", "b", "c", "d", "e"},
+			expectError: ErrTokenLoop,
+			expectCode:  codes.Unknown,
+		},
+		"same token": {
+			client: &pagingControllerClient{
+				ids:       ids,
+				nextToken: func(token string) string { return "2" },
+			},
+			pageSize:    2,
+			expectIDs:   []string{"a", "b", "c", "d"},
+			expectError: ErrTokenLoop,
+			expectCode:  codes.Unknown,
+		},
+		"invalid token": {
+			client: &pagingControllerClient{
+				ids: ids,
+				err: map[string]error{"2": status.Error(codes.Aborted, "token expired")},
+			},
+			pageSize:    2,
+			expectIDs:   []string{"a", "b"},
+			expectError: ErrInvalidStartingToken,
+			expectCode:  codes.Aborted,
+		},
+		"aborted first page": {
+			client: &pagingControllerClient{
+				ids: ids,
+				err: map[string]error{"": status.Error(codes.Aborted, "busy")},
+			},
+			expectCode: codes.Aborted,
+		},
+		"other error": {
+			client: &pagingControllerClient{
+				ids: ids,
+				err: map[string]error{"4": status.Error(codes.Internal, "oops")},
+			},
+			pageSize:   2,
+			expectIDs:  []string{"a", "b", "c", "d"},
+			expectCode: codes.Internal,
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			var actualIDs []string
+			var actualErr error
+			for entry, err := range ListVolumes(context.Background(), tc.client, tc.pageSize) {
+				if err != nil {
+					require.NoError(t, actualErr, "only one error expected")
+					actualErr = err
+					continue
+				}
+				actualIDs = append(actualIDs, entry.GetVolume().GetVolumeId())
+			}
+			assert.Equal(t, tc.expectIDs, actualIDs, "volumes")
+			switch {
+			case tc.expectError != nil:
+				assert.ErrorIs(t, actualErr, tc.expectError)
+			case tc.expectCode == codes.OK:
+				assert.NoError(t, actualErr)
+			default:
+				assert.False(t, errors.Is(actualErr, ErrInvalidStartingToken), "should not be an invalid token error: %v", actualErr)
+			}
+			assert.Equal(t, tc.expectCode, status.Code(actualErr), "gRPC status code")
+		})
+	}
+}
+
+func TestListSnapshots(t *testing.T) {
+	client := &pagingControllerClient{ids: []string{"a", "b", "c"}}
+	filter := &csi.ListSnapshotsRequest{
+		SourceVolumeId: "vol",
+		StartingToken:  "ignored",
+		Secrets:        map[string]string{"key": "value"},
+	}
+	var actualIDs []string
+	for entry, err := range ListSnapshots(context.Background(), client, 1, filter) {
+		require.NoError(t, err)
+		actualIDs = append(actualIDs, entry.GetSnapshot().GetSnapshotId())
+		if len(actualIDs) == 2 {
+			break
+		}
+	}
+	assert.Equal(t, []string{"a", "b"}, actualIDs)
+	require.Len(t, client.requests, 2, "requests")
+	for i, req := range client.requests {
+		assert.Equal(t, "vol", req.SourceVolumeId, "request #%d: SourceVolumeId", i)
+		assert.Equal(t, map[string]string{"key": "value"}, req.Secrets, "request #%d: Secrets", i)
+		assert.Equal(t, int32(1), req.MaxEntries, "request #%d: MaxEntries", i)
+	}
+	assert.Equal(t, "", client.requests[0].StartingToken)
+	assert.Equal(t, "1", client.requests[1].StartingToken)
+	assert.Equal(t, "ignored", filter.StartingToken, "filter must not be modified")
+}
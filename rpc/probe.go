@@ -0,0 +1,283 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/component-base/metrics"
+	"k8s.io/klog/v2"
+
+	csimetrics "github.com/kubernetes-csi/csi-lib-utils/metrics"
+)
+
+const (
+	// Values of the "result" label of the probe metrics.
+	probeResultReady    = "ready"
+	probeResultNotReady = "not-ready"
+	probeResultFailed   = "failed"
+	probeResultTimeout  = "timeout"
+	probeResultCanceled = "canceled"
+)
+
+// ProbeAttempt describes the result of one Probe call made by
+// ProbeForeverWithOptions.
+type ProbeAttempt struct {
+	// Attempt counts the calls, starting at 1.
+	Attempt int
+	// Ready is the result of a successful call.
+	Ready bool
+	// Err is the error returned by the call, nil if successful.
+	Err error
+	// Duration is the time that the call took.
+	Duration time.Duration
+	// Elapsed is the time since ProbeForeverWithOptions started.
+	Elapsed time.Duration
+}
+
+// ProbeOption is used to pass optional configuration to ProbeForeverWithOptions.
+type ProbeOption func(*probeOptions)
+
+type probeOptions struct {
+	initialInterval time.Duration
+	factor          float64
+	maxInterval     time.Duration
+	retryableCodes  []codes.Code
+	maxWait         time.Duration
+	callback        func(ProbeAttempt)
+	metrics         *probeMetrics
+}
+
+// WithProbeBackoff changes the interval between probes. The first
+// interval is initial, then each interval is the previous one
+// multiplied by factor, up to maxInterval. The default is a
+// constant interval of one second.
+//
+// Invalid values get clamped: an initial interval <= 0 is replaced by
+// the default of one second, a factor < 1 by 1 and a maxInterval
+// smaller than the initial interval by the initial interval.
+func WithProbeBackoff(initial time.Duration, factor float64, maxInterval time.Duration) ProbeOption {
+	if initial <= 0 {
+		initial = probeInterval
+	}
+	if factor < 1 {
+		factor = 1
+	}
+	if maxInterval < initial {
+		maxInterval = initial
+	}
+	return func(o *probeOptions) {
+		o.initialInterval = initial
+		o.factor = factor
+		o.maxInterval = maxInterval
+	}
+}
+
+// WithProbeRetryableCodes defines which gRPC status codes are treated as
+// "driver not ready yet" instead of a permanent failure. The default
+// is DeadlineExceeded, which covers a single probe timing out.
+func WithProbeRetryableCodes(retryableCodes ...codes.Code) ProbeOption {
+	return func(o *probeOptions) {
+		o.retryableCodes = retryableCodes
+	}
+}
+
+// WithProbeMaxWait limits the total time spent waiting for the driver.
+// Zero, the default, waits until the context is canceled.
+func WithProbeMaxWait(maxWait time.Duration) ProbeOption {
+	return func(o *probeOptions) {
+		o.maxWait = maxWait
+	}
+}
+
+// WithProbeCallback registers a function that gets called after each probe.
+func WithProbeCallback(callback func(ProbeAttempt)) ProbeOption {
+	return func(o *probeOptions) {
+		o.callback = callback
+	}
+}
+
+// WithProbeMetrics records the probe attempts and the final outcome in
+// the registry, typically the one from CSIMetricsManager.GetRegistry.
+// The subsystem should be the same as the one of that manager, for
+// example metrics.SubsystemSidecar:
+//   - <subsystem>_probe_attempts_total counts Probe calls by result,
+//     which is "ready", "not-ready" or the gRPC status code of the error
+//   - <subsystem>_probe_wait_seconds is a histogram of the total time
+//     until the driver was ready or waiting stopped, with "ready",
+//     "failed", "timeout" or "canceled" as result
+//
+// The metrics are registered once per registry. Calling WithProbeMetrics
+// again with the same registry reuses them.
+func WithProbeMetrics(registry metrics.KubeRegistry, subsystem string) ProbeOption {
+	m := newProbeMetrics(subsystem)
+	m.attempts = registerOrReuse(registry, m.attempts)
+	m.wait = registerOrReuse(registry, m.wait)
+	return func(o *probeOptions) {
+		o.metrics = m
+	}
+}
+
+// ProbeForeverWithOptions calls Probe() of a CSI driver and waits until the driver
+// becomes ready. In contrast to ProbeForever, the retry behavior is configurable.
+// Any error with a gRPC status code that is not retryable is returned.
+func ProbeForeverWithOptions(ctx context.Context, conn *grpc.ClientConn, singleProbeTimeout time.Duration, options ...ProbeOption) error {
+	o := probeOptions{
+		initialInterval: probeInterval,
+		factor:          1,
+		maxInterval:     probeInterval,
+		retryableCodes:  []codes.Code{codes.DeadlineExceeded},
+	}
+	for _, option := range options {
+		option(&o)
+	}
+
+	logger := klog.FromContext(ctx)
+	start := time.Now()
+	interval := o.initialInterval
+
+	for attempt := 1; ; attempt++ {
+		// Run the probe once before waiting for the timer
+		logger.Info("Probing CSI driver for readiness", "attempt", attempt)
+		probeStart := time.Now()
+		ready, err := probeOnce(ctx, conn, singleProbeTimeout)
+		o.recordAttempt(ProbeAttempt{
+			Attempt:  attempt,
+			Ready:    ready,
+			Err:      err,
+			Duration: time.Since(probeStart),
+			Elapsed:  time.Since(start),
+		})
+		if err != nil {
+			st, ok := status.FromError(err)
+			if !ok {
+				// This is not gRPC error. The probe must have failed before gRPC
+				// method was called, otherwise we would get gRPC error.
+				o.recordOutcome(probeResultFailed, start)
+				return fmt.Errorf("CSI driver probe failed: %s", err)
+			}
+			if !slices.Contains(o.retryableCodes, st.Code()) {
+				o.recordOutcome(probeResultFailed, start)
+				return fmt.Errorf("CSI driver probe failed: %s", err)
+			}
+			// Driver is not ready. Fall through to sleep() below.
+			logger.Info("CSI driver probe failed, retrying", "code", st.Code(), "message", st.Message())
+		} else {
+			if ready {
+				o.recordOutcome(probeResultReady, start)
+				return nil
+			}
+			logger.Info("CSI driver is not ready")
+		}
+
+		if o.maxWait > 0 && time.Since(start)+interval > o.maxWait {
+			o.recordOutcome(probeResultTimeout, start)
+			return fmt.Errorf("CSI driver not ready after %d probes within %s", attempt, o.maxWait)
+		}
+		select {
+		case <-ctx.Done():
+			o.recordOutcome(probeResultCanceled, start)
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+		interval = time.Duration(float64(interval) * o.factor)
+		if interval > o.maxInterval {
+			interval = o.maxInterval
+		}
+	}
+}
+
+func (o *probeOptions) recordAttempt(attempt ProbeAttempt) {
+	if o.callback != nil {
+		o.callback(attempt)
+	}
+	if o.metrics != nil {
+		result := probeResultNotReady
+		switch {
+		case attempt.Err != nil:
+			result = csimetrics.ErrorCode(attempt.Err)
+		case attempt.Ready:
+			result = probeResultReady
+		}
+		o.metrics.attempts.WithLabelValues(result).Inc()
+	}
+}
+
+func (o *probeOptions) recordOutcome(result string, start time.Time) {
+	if o.metrics != nil {
+		o.metrics.wait.WithLabelValues(result).Observe(time.Since(start).Seconds())
+	}
+}
+
+type probeMetrics struct {
+	attempts *metrics.CounterVec
+	wait     *metrics.HistogramVec
+}
+
+func newProbeMetrics(subsystem string) *probeMetrics {
+	return &probeMetrics{
+		attempts: metrics.NewCounterVec(
+			&metrics.CounterOpts{
+				Subsystem:      subsystem,
+				Name:           "probe_attempts_total",
+				Help:           "Number of CSI driver Probe calls made while waiting for the driver to become ready",
+				StabilityLevel: metrics.ALPHA,
+			},
+			[]string{"result"},
+		),
+		wait: metrics.NewHistogramVec(
+			&metrics.HistogramOpts{
+				Subsystem:      subsystem,
+				Name:           "probe_wait_seconds",
+				Help:           "Time spent waiting for the CSI driver to become ready",
+				Buckets:        csimetrics.DefaultLatencyBuckets(),
+				StabilityLevel: metrics.ALPHA,
+			},
+			[]string{"result"},
+		),
+	}
+}
+
+// registerOrReuse registers the collector or, if an identical one was
+// registered before, returns that one. Other errors are logged and the
+// unregistered collector is returned, which then still works but is not
+// exported.
+func registerOrReuse[C interface {
+	metrics.Registerable
+	*metrics.CounterVec | *metrics.HistogramVec
+}](registry metrics.KubeRegistry, collector C) C {
+	err := registry.Register(collector)
+	if err == nil {
+		return collector
+	}
+	var alreadyRegistered prometheus.AlreadyRegisteredError
+	if errors.As(err, &alreadyRegistered) {
+		if existing, ok := alreadyRegistered.ExistingCollector.(C); ok {
+			return existing
+		}
+	}
+	klog.Background().Error(err, "Failed to register probe metric")
+	return collector
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rpc
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubernetes-csi/csi-lib-utils/connection"
+	csimetrics "github.com/kubernetes-csi/csi-lib-utils/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/testutil"
+	"k8s.io/klog/v2/ktesting"
+)
+
+func TestProbeForeverWithOptions(t *testing.T) {
+	unavailable := probeCall{err: status.Error(codes.Unavailable, "starting")}
+	notReady := probeCall{response: &csi.ProbeResponse{Ready: &wrapperspb.BoolValue{Value: false}}}
+	ready := probeCall{response: &csi.ProbeResponse{Ready: &wrapperspb.BoolValue{Value: true}}}
+	fastBackoff := WithProbeBackoff(time.Millisecond, 2, 10*time.Millisecond)
+
+	tests := []struct {
+		name            string
+		probeCalls      []probeCall
+		options         []ProbeOption
+		expectError     string
+		expectAttempts  int
+		expectOutcome   string
+		expectedMetrics string
+	}{
+		{
+			name:           "unavailable not retryable",
+			probeCalls:     []probeCall{unavailable},
+			options:        []ProbeOption{fastBackoff},
+			expectError:    "CSI driver probe failed: rpc error: code = Unavailable desc = starting",
+			expectAttempts: 1,
+			expectOutcome:  probeResultFailed,
+			expectedMetrics: `
+				# HELP csi_sidecar_probe_attempts_total [ALPHA] Number of CSI driver Probe calls made while waiting for the driver to become ready
+				# TYPE csi_sidecar_probe_attempts_total counter
+				csi_sidecar_probe_attempts_total{result="Unavailable"} 1
+			`,
+		},
+		{
+			name:           "unavailable retryable",
+			probeCalls:     []probeCall{unavailable, unavailable, notReady, ready},
+			options:        []ProbeOption{fastBackoff, WithProbeRetryableCodes(codes.Unavailable, codes.DeadlineExceeded)},
+			expectAttempts: 4,
+			expectOutcome:  probeResultReady,
+			expectedMetrics: `
+				# HELP csi_sidecar_probe_attempts_total [ALPHA] Number of CSI driver Probe calls made while waiting for the driver to become ready
+				# TYPE csi_sidecar_probe_attempts_total counter
+				csi_sidecar_probe_attempts_total{result="Unavailable"} 2
+				csi_sidecar_probe_attempts_total{result="not-ready"} 1
+				csi_sidecar_probe_attempts_total{result="ready"} 1
+			`,
+		},
+		{
+			name: "max wait",
+			probeCalls: []probeCall{
+				notReady, notReady, notReady, notReady, notReady, notReady, notReady, notReady, notReady, notReady,
+				notReady, notReady, notReady, notReady, notReady, notReady, notReady, notReady, notReady, notReady,
+			},
+			options:       []ProbeOption{WithProbeBackoff(10*time.Millisecond, 1, 10*time.Millisecond), WithProbeMaxWait(35 * time.Millisecond)},
+			expectError:   "CSI driver not ready after",
+			expectOutcome: probeResultTimeout,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tmp := tmpDir(t)
+			defer os.RemoveAll(tmp)
+			identity := &fakeIdentityServer{
+				probeCalls: test.probeCalls,
+			}
+			addr, stopServer := startServer(t, tmp, identity, nil, nil, nil)
+			defer stopServer()
+
+			_, ctx := ktesting.NewTestContext(t)
+			conn, err := connection.Connect(ctx, addr, nil)
+			require.NoError(t, err, "connect")
+			defer conn.Close()
+
+			var attempts []ProbeAttempt
+			registry := metrics.NewKubeRegistry()
+			metricsOption := WithProbeMetrics(registry, csimetrics.SubsystemSidecar)
+			options := append(test.options,
+				WithProbeCallback(func(attempt ProbeAttempt) {
+					attempts = append(attempts, attempt)
+				}),
+				metricsOption,
+			)
+			err = ProbeForeverWithOptions(ctx, conn, time.Second, options...)
+			if test.expectError != "" {
+				if assert.Error(t, err) {
+					assert.True(t, strings.HasPrefix(err.Error(), test.expectError), "expected error %q, got %q", test.expectError, err.Error())
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, identity.probeCallCount, len(attempts), "number of callback invocations")
+			if test.expectAttempts > 0 {
+				assert.Equal(t, test.expectAttempts, len(attempts), "number of attempts")
+			}
+			for i, attempt := range attempts {
+				assert.Equal(t, i+1, attempt.Attempt, "attempt counter")
+			}
+			if test.expectedMetrics != "" {
+				assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(test.expectedMetrics), "csi_sidecar_probe_attempts_total"))
+			}
+			// Calling WithProbeMetrics again must reuse the registered metrics.
+			var o probeOptions
+			WithProbeMetrics(registry, csimetrics.SubsystemSidecar)(&o)
+			count, err := testutil.GetHistogramMetricCount(o.metrics.wait.WithLabelValues(test.expectOutcome))
+			if assert.NoError(t, err) {
+				assert.Equal(t, uint64(1), count, "outcome recorded")
+			}
+		})
+	}
+}
+
+func TestWithProbeMetrics(t *testing.T) {
+	registry := metrics.NewKubeRegistry()
+	var first, second probeOptions
+	WithProbeMetrics(registry, csimetrics.SubsystemPlugin)(&first)
+	WithProbeMetrics(registry, csimetrics.SubsystemPlugin)(&second)
+	assert.Same(t, first.metrics.attempts, second.metrics.attempts, "attempts metric reused")
+	assert.Same(t, first.metrics.wait, second.metrics.wait, "wait metric reused")
+
+	second.recordAttempt(ProbeAttempt{Attempt: 1, Ready: true})
+	expectedMetrics := `
+		# HELP csi_plugin_probe_attempts_total [ALPHA] Number of CSI driver Probe calls made while waiting for the driver to become ready
+		# TYPE csi_plugin_probe_attempts_total counter
+		csi_plugin_probe_attempts_total{result="ready"} 1
+	`
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expectedMetrics), "csi_plugin_probe_attempts_total"))
+}
+
+func TestWithProbeBackoff(t *testing.T) {
+	tests := []struct {
+		name              string
+		initial           time.Duration
+		factor            float64
+		maxInterval       time.Duration
+		expectInitial     time.Duration
+		expectFactor      float64
+		expectMaxInterval time.Duration
+	}{
+		{
+			name:              "valid",
+			initial:           time.Millisecond,
+			factor:            2,
+			maxInterval:       time.Second,
+			expectInitial:     time.Millisecond,
+			expectFactor:      2,
+			expectMaxInterval: time.Second,
+		},
+		{
+			name:              "zero",
+			expectInitial:     probeInterval,
+			expectFactor:      1,
+			expectMaxInterval: probeInterval,
+		},
+		{
+			name:              "negative initial",
+			initial:           -time.Second,
+			factor:            2,
+			maxInterval:       time.Minute,
+			expectInitial:     probeInterval,
+			expectFactor:      2,
+			expectMaxInterval: time.Minute,
+		},
+		{
+			name:              "factor less than one",
+			initial:           time.Second,
+			factor:            0.5,
+			maxInterval:       time.Minute,
+			expectInitial:     time.Second,
+			expectFactor:      1,
+			expectMaxInterval: time.Minute,
+		},
+		{
+			name:              "max interval below initial",
+			initial:           time.Minute,
+			factor:            2,
+			maxInterval:       time.Second,
+			expectInitial:     time.Minute,
+			expectFactor:      2,
+			expectMaxInterval: time.Minute,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var o probeOptions
+			WithProbeBackoff(test.initial, test.factor, test.maxInterval)(&o)
+			assert.Equal(t, test.expectInitial, o.initialInterval, "initial interval")
+			assert.Equal(t, test.expectFactor, o.factor, "factor")
+			assert.Equal(t, test.expectMaxInterval, o.maxInterval, "max interval")
+		})
+	}
+}
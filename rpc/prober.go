@@ -0,0 +1,243 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"k8s.io/klog/v2"
+
+	"github.com/kubernetes-csi/csi-lib-utils/metrics"
+)
+
+const (
+	defaultProberInterval = 10 * time.Second
+	defaultProberTimeout  = time.Second
+
+	// HealthzPath is where RegisterToServer serves the liveness check.
+	HealthzPath = "/healthz"
+	// ReadyzPath is where RegisterToServer serves the readiness check.
+	ReadyzPath = "/readyz"
+)
+
+// ProbeResult is the outcome of one Probe call made by a Prober.
+type ProbeResult struct {
+	// Time is when the call was made.
+	Time time.Time
+	// Ready is the result of a successful call.
+	Ready bool
+	// Err is the error returned by the call, nil if successful.
+	Err error
+}
+
+// Prober calls Probe periodically in the background and caches the result.
+// Its HTTP handlers serve that result, which makes it possible to
+// implement liveness and readiness checks of a CSI driver in any sidecar,
+// without running the separate livenessprobe sidecar.
+type Prober struct {
+	conn              *grpc.ClientConn
+	interval          time.Duration
+	timeout           time.Duration
+	livenessThreshold time.Duration
+	now               func() time.Time
+
+	mutex       sync.RWMutex
+	created     time.Time
+	lastResult  *ProbeResult
+	lastSuccess time.Time
+}
+
+// ProberOption is used to pass optional configuration to NewProber.
+type ProberOption func(*Prober)
+
+// WithProberInterval changes how often Probe gets called. The default is 10 seconds.
+// An interval <= 0 is ignored and the default is used instead.
+func WithProberInterval(interval time.Duration) ProberOption {
+	return func(p *Prober) {
+		if interval > 0 {
+			p.interval = interval
+		}
+	}
+}
+
+// WithProberTimeout changes the timeout for a single Probe call. The default is one second.
+// A timeout <= 0 is ignored and the default is used instead.
+func WithProberTimeout(timeout time.Duration) ProberOption {
+	return func(p *Prober) {
+		if timeout > 0 {
+			p.timeout = timeout
+		}
+	}
+}
+
+// WithProberLivenessThreshold defines how long the driver may fail to
+// report that it is ready before the liveness check fails. The default
+// is three times the probe interval. A threshold <= 0 is ignored and the
+// default is used instead.
+func WithProberLivenessThreshold(threshold time.Duration) ProberOption {
+	return func(p *Prober) {
+		if threshold > 0 {
+			p.livenessThreshold = threshold
+		}
+	}
+}
+
+// NewProber creates a prober for the connection. Run must be called to start probing.
+func NewProber(conn *grpc.ClientConn, options ...ProberOption) *Prober {
+	p := &Prober{
+		conn:     conn,
+		interval: defaultProberInterval,
+		timeout:  defaultProberTimeout,
+		now:      time.Now,
+	}
+	for _, option := range options {
+		option(p)
+	}
+	if p.livenessThreshold == 0 {
+		p.livenessThreshold = 3 * p.interval
+	}
+	p.created = p.now()
+	return p
+}
+
+// Run calls Probe immediately and then periodically until the context is canceled.
+func (p *Prober) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.probe(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probe calls Probe once and stores the result.
+func (p *Prober) probe(ctx context.Context) {
+	logger := klog.FromContext(ctx)
+	result := ProbeResult{Time: p.now()}
+	result.Ready, result.Err = probeOnce(ctx, p.conn, p.timeout)
+	switch {
+	case result.Err != nil:
+		logger.V(2).Info("CSI driver probe failed", "err", result.Err)
+	case !result.Ready:
+		logger.V(2).Info("CSI driver is not ready")
+	default:
+		logger.V(5).Info("CSI driver is ready")
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.lastResult = &result
+	if result.Err == nil && result.Ready {
+		p.lastSuccess = result.Time
+	}
+}
+
+// LastResult returns the result of the most recent Probe call,
+// nil if none has completed yet.
+func (p *Prober) LastResult() *ProbeResult {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	if p.lastResult == nil {
+		return nil
+	}
+	result := *p.lastResult
+	return &result
+}
+
+// LastSuccess returns the time of the most recent Probe call which
+// reported that the driver is ready, the zero time if there was none.
+func (p *Prober) LastSuccess() time.Time {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.lastSuccess
+}
+
+// Healthy returns nil if the driver was ready within the liveness threshold.
+// To avoid failing while the driver starts, the time when the prober was
+// created counts as a success.
+func (p *Prober) Healthy() error {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	last := p.lastSuccess
+	if last.IsZero() {
+		last = p.created
+	}
+	if since := p.now().Sub(last); since > p.livenessThreshold {
+		if p.lastResult != nil && p.lastResult.Err != nil {
+			return fmt.Errorf("CSI driver not ready for %s, last probe failed: %w", since.Round(time.Second), p.lastResult.Err)
+		}
+		return fmt.Errorf("CSI driver not ready for %s", since.Round(time.Second))
+	}
+	return nil
+}
+
+// Ready returns nil if the most recent Probe call reported that the driver is ready.
+func (p *Prober) Ready() error {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	switch {
+	case p.lastResult == nil:
+		return fmt.Errorf("CSI driver not probed yet")
+	case p.lastResult.Err != nil:
+		return fmt.Errorf("CSI driver probe failed: %w", p.lastResult.Err)
+	case !p.lastResult.Ready:
+		return fmt.Errorf("CSI driver is not ready")
+	}
+	return nil
+}
+
+// HealthzHandler returns an HTTP handler which responds with
+// 200 if Healthy returns nil and 503 otherwise.
+func (p *Prober) HealthzHandler() http.Handler {
+	return checkHandler(p.Healthy)
+}
+
+// ReadyzHandler returns an HTTP handler which responds with
+// 200 if Ready returns nil and 503 otherwise.
+func (p *Prober) ReadyzHandler() http.Handler {
+	return checkHandler(p.Ready)
+}
+
+// RegisterToServer registers the handlers at HealthzPath and ReadyzPath,
+// typically on the same server that also serves metrics.
+func (p *Prober) RegisterToServer(s metrics.Server) {
+	s.Handle(HealthzPath, p.HealthzHandler())
+	s.Handle(ReadyzPath, p.ReadyzHandler())
+}
+
+func checkHandler(check func() error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if err := check(); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintln(w, err.Error())
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+}
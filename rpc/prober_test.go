@@ -0,0 +1,177 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubernetes-csi/csi-lib-utils/connection"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"k8s.io/klog/v2/ktesting"
+)
+
+func TestProber(t *testing.T) {
+	tmp := tmpDir(t)
+	defer os.RemoveAll(tmp)
+	identity := &fakeIdentityServer{
+		probeCalls: []probeCall{
+			{response: &csi.ProbeResponse{Ready: &wrapperspb.BoolValue{Value: true}}},
+			{err: status.Error(codes.Unavailable, "restarting")},
+			{response: &csi.ProbeResponse{Ready: &wrapperspb.BoolValue{Value: false}}},
+		},
+	}
+	addr, stopServer := startServer(t, tmp, identity, nil, nil, nil)
+	defer stopServer()
+
+	_, ctx := ktesting.NewTestContext(t)
+	conn, err := connection.Connect(ctx, addr, nil)
+	require.NoError(t, err, "connect")
+	defer conn.Close()
+
+	now := time.Now()
+	prober := NewProber(conn, WithProberLivenessThreshold(time.Minute))
+	prober.now = func() time.Time { return now }
+	prober.created = now
+	mux := http.NewServeMux()
+	prober.RegisterToServer(mux)
+
+	check := func(name string, expectHealthy, expectReady bool) {
+		t.Helper()
+		for path, expectOK := range map[string]bool{HealthzPath: expectHealthy, ReadyzPath: expectReady} {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			expectCode := http.StatusOK
+			if !expectOK {
+				expectCode = http.StatusServiceUnavailable
+			}
+			assert.Equal(t, expectCode, rec.Code, "%s: %s: %s", name, path, rec.Body.String())
+		}
+	}
+
+	check("not probed yet", true, false)
+	assert.Nil(t, prober.LastResult(), "no result yet")
+	assert.True(t, prober.LastSuccess().IsZero(), "no success yet")
+
+	prober.probe(ctx)
+	check("ready", true, true)
+	if result := prober.LastResult(); assert.NotNil(t, result) {
+		assert.True(t, result.Ready)
+		assert.NoError(t, result.Err)
+	}
+	assert.Equal(t, now, prober.LastSuccess())
+
+	now = now.Add(30 * time.Second)
+	prober.probe(ctx)
+	check("failed within threshold", true, false)
+	if result := prober.LastResult(); assert.NotNil(t, result) {
+		assert.Equal(t, codes.Unavailable, status.Code(result.Err))
+	}
+
+	now = now.Add(time.Minute)
+	prober.probe(ctx)
+	check("not ready beyond threshold", false, false)
+	assert.Equal(t, now.Add(-90*time.Second), prober.LastSuccess(), "last success")
+}
+
+func TestProberRun(t *testing.T) {
+	tmp := tmpDir(t)
+	defer os.RemoveAll(tmp)
+	identity := &fakeIdentityServer{
+		probeCalls: []probeCall{
+			{response: &csi.ProbeResponse{}},
+		},
+	}
+	addr, stopServer := startServer(t, tmp, identity, nil, nil, nil)
+	defer stopServer()
+
+	_, ctx := ktesting.NewTestContext(t)
+	conn, err := connection.Connect(ctx, addr, nil)
+	require.NoError(t, err, "connect")
+	defer conn.Close()
+
+	prober := NewProber(conn, WithProberInterval(time.Hour))
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		prober.Run(ctx)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return prober.Ready() == nil
+	}, 10*time.Second, 10*time.Millisecond, "driver becomes ready")
+	cancel()
+	<-done
+}
+
+func TestProberOptions(t *testing.T) {
+	testcases := map[string]struct {
+		options                 []ProberOption
+		expectInterval          time.Duration
+		expectTimeout           time.Duration
+		expectLivenessThreshold time.Duration
+	}{
+		"defaults": {
+			expectInterval:          defaultProberInterval,
+			expectTimeout:           defaultProberTimeout,
+			expectLivenessThreshold: 3 * defaultProberInterval,
+		},
+		"valid": {
+			options:                 []ProberOption{WithProberInterval(time.Minute), WithProberTimeout(5 * time.Second), WithProberLivenessThreshold(time.Hour)},
+			expectInterval:          time.Minute,
+			expectTimeout:           5 * time.Second,
+			expectLivenessThreshold: time.Hour,
+		},
+		"zero": {
+			options:                 []ProberOption{WithProberInterval(0), WithProberTimeout(0), WithProberLivenessThreshold(0)},
+			expectInterval:          defaultProberInterval,
+			expectTimeout:           defaultProberTimeout,
+			expectLivenessThreshold: 3 * defaultProberInterval,
+		},
+		"negative": {
+			options:                 []ProberOption{WithProberInterval(-time.Second), WithProberTimeout(-time.Second), WithProberLivenessThreshold(-time.Second)},
+			expectInterval:          defaultProberInterval,
+			expectTimeout:           defaultProberTimeout,
+			expectLivenessThreshold: 3 * defaultProberInterval,
+		},
+		"negative threshold with interval": {
+			options:                 []ProberOption{WithProberInterval(time.Minute), WithProberLivenessThreshold(-time.Minute)},
+			expectInterval:          time.Minute,
+			expectTimeout:           defaultProberTimeout,
+			expectLivenessThreshold: 3 * time.Minute,
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			prober := NewProber(nil, tc.options...)
+			assert.Equal(t, tc.expectInterval, prober.interval, "interval")
+			assert.Equal(t, tc.expectTimeout, prober.timeout, "timeout")
+			assert.Equal(t, tc.expectLivenessThreshold, prober.livenessThreshold, "liveness threshold")
+		})
+	}
+}
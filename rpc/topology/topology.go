@@ -0,0 +1,230 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package topology converts between CSI topology segments and the
+// Kubernetes representations of topology: node labels, node selector
+// terms and the allowedTopologies of a StorageClass.
+//
+// In Kubernetes, the segments returned by NodeGetInfo become labels of
+// the node object, so segment keys must be valid label keys and segment
+// values valid label values.
+package topology
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ValidateSegments checks that the keys of the segments are valid
+// Kubernetes label keys and that the values are valid label values.
+// All problems are reported together.
+func ValidateSegments(segments map[string]string) error {
+	var errs []error
+	for _, key := range slices.Sorted(maps.Keys(segments)) {
+		for _, msg := range validation.IsQualifiedName(key) {
+			errs = append(errs, fmt.Errorf("topology key %q: %s", key, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(segments[key]) {
+			errs = append(errs, fmt.Errorf("topology key %q: value %q: %s", key, segments[key], msg))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FromNodeLabels extracts the topology of a node from its labels. keys
+// are the topology keys of the driver, as listed in the CSINode object.
+// All of them must be set.
+func FromNodeLabels(labels map[string]string, keys []string) (*csi.Topology, error) {
+	segments := make(map[string]string, len(keys))
+	for _, key := range keys {
+		value, ok := labels[key]
+		if !ok {
+			return nil, fmt.Errorf("node has no label for topology key %q", key)
+		}
+		segments[key] = value
+	}
+	return &csi.Topology{Segments: segments}, nil
+}
+
+// ToNodeSelectorTerm returns a term which matches all nodes that have
+// the topology. The expressions are sorted by key.
+func ToNodeSelectorTerm(topology *csi.Topology) v1.NodeSelectorTerm {
+	var term v1.NodeSelectorTerm
+	segments := topology.GetSegments()
+	for _, key := range slices.Sorted(maps.Keys(segments)) {
+		term.MatchExpressions = append(term.MatchExpressions, v1.NodeSelectorRequirement{
+			Key:      key,
+			Operator: v1.NodeSelectorOpIn,
+			Values:   []string{segments[key]},
+		})
+	}
+	return term
+}
+
+// ToNodeSelector returns a selector which matches all nodes that have
+// one of the topologies, for example for the node affinity of a
+// PersistentVolume.
+func ToNodeSelector(topologies []*csi.Topology) *v1.NodeSelector {
+	selector := &v1.NodeSelector{}
+	for _, topology := range topologies {
+		selector.NodeSelectorTerms = append(selector.NodeSelectorTerms, ToNodeSelectorTerm(topology))
+	}
+	return selector
+}
+
+// FromNodeSelectorTerm is the reverse of ToNodeSelectorTerm. Only the
+// In operator on node labels is supported. An expression with more than
+// one value expands into one topology per value, so a term with
+// several such expressions results in all combinations.
+func FromNodeSelectorTerm(term v1.NodeSelectorTerm) ([]*csi.Topology, error) {
+	if len(term.MatchFields) > 0 {
+		return nil, errors.New("node selector fields are not supported")
+	}
+	expressions := make([]v1.TopologySelectorLabelRequirement, 0, len(term.MatchExpressions))
+	for _, expr := range term.MatchExpressions {
+		if expr.Operator != v1.NodeSelectorOpIn {
+			return nil, fmt.Errorf("key %q: unsupported operator %q", expr.Key, expr.Operator)
+		}
+		expressions = append(expressions, v1.TopologySelectorLabelRequirement{Key: expr.Key, Values: expr.Values})
+	}
+	return expand(expressions)
+}
+
+// FromTopologySelectorTerms converts allowedTopologies into the
+// topologies which are allowed, without duplicates and in the order in
+// which they first appear.
+func FromTopologySelectorTerms(terms []v1.TopologySelectorTerm) ([]*csi.Topology, error) {
+	var result []*csi.Topology
+	for i, term := range terms {
+		topologies, err := expand(term.MatchLabelExpressions)
+		if err != nil {
+			return nil, fmt.Errorf("term #%d: %w", i, err)
+		}
+		for _, topology := range topologies {
+			if !slices.ContainsFunc(result, func(t *csi.Topology) bool { return maps.Equal(t.Segments, topology.Segments) }) {
+				result = append(result, topology)
+			}
+		}
+	}
+	return result, nil
+}
+
+// NewTopologyRequirement computes the accessibility requirements for
+// CreateVolume.
+//
+// The requisite topologies are the allowedTopologies of the
+// StorageClass. The preferred topologies contain the same entries,
+// with those that match the preferred topology (usually the topology of
+// the node selected by the scheduler) moved to the front. The preferred
+// topology may be nil. A preferred topology without segments is treated
+// like nil.
+//
+// Without allowedTopologies, the preferred topology becomes the only
+// requisite topology. The result is nil if both are empty, which means
+// that the volume has no topology constraints.
+func NewTopologyRequirement(allowedTopologies []v1.TopologySelectorTerm, preferred *csi.Topology) (*csi.TopologyRequirement, error) {
+	requisite, err := FromTopologySelectorTerms(allowedTopologies)
+	if err != nil {
+		return nil, err
+	}
+	if len(preferred.GetSegments()) == 0 {
+		preferred = nil
+	}
+	if len(requisite) == 0 {
+		if preferred == nil {
+			return nil, nil
+		}
+		return &csi.TopologyRequirement{
+			Requisite: []*csi.Topology{preferred},
+			Preferred: []*csi.Topology{preferred},
+		}, nil
+	}
+
+	var first, rest []*csi.Topology
+	for _, topology := range requisite {
+		if preferred != nil && Matches(topology, preferred.GetSegments()) {
+			first = append(first, topology)
+		} else {
+			rest = append(rest, topology)
+		}
+	}
+	if preferred != nil && len(first) == 0 {
+		return nil, fmt.Errorf("preferred topology %s is not allowed", String(preferred))
+	}
+	return &csi.TopologyRequirement{
+		Requisite: requisite,
+		Preferred: append(first, rest...),
+	}, nil
+}
+
+// Matches returns true if all segments of the topology are also set to
+// the same value in the labels. The labels may contain additional keys.
+func Matches(topology *csi.Topology, labels map[string]string) bool {
+	for key, value := range topology.GetSegments() {
+		if actual, ok := labels[key]; !ok || actual != value {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the segments as comma-separated key=value pairs,
+// sorted by key.
+func String(topology *csi.Topology) string {
+	segments := topology.GetSegments()
+	pairs := make([]string, 0, len(segments))
+	for _, key := range slices.Sorted(maps.Keys(segments)) {
+		pairs = append(pairs, key+"="+segments[key])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// expand returns all combinations of the values of the expressions.
+func expand(expressions []v1.TopologySelectorLabelRequirement) ([]*csi.Topology, error) {
+	if len(expressions) == 0 {
+		return nil, nil
+	}
+	combinations := []map[string]string{{}}
+	for _, expr := range expressions {
+		if len(expr.Values) == 0 {
+			return nil, fmt.Errorf("key %q: no values", expr.Key)
+		}
+		var next []map[string]string
+		for _, segments := range combinations {
+			if _, ok := segments[expr.Key]; ok {
+				return nil, fmt.Errorf("key %q: used more than once", expr.Key)
+			}
+			for _, value := range expr.Values {
+				extended := maps.Clone(segments)
+				extended[expr.Key] = value
+				next = append(next, extended)
+			}
+		}
+		combinations = next
+	}
+	topologies := make([]*csi.Topology, 0, len(combinations))
+	for _, segments := range combinations {
+		topologies = append(topologies, &csi.Topology{Segments: segments})
+	}
+	return topologies, nil
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package topology
+
+import (
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	zoneKey   = "topology.example.com/zone"
+	regionKey = "topology.example.com/region"
+)
+
+func topology(pairs ...string) *csi.Topology {
+	segments := map[string]string{}
+	for i := 0; i < len(pairs); i += 2 {
+		segments[pairs[i]] = pairs[i+1]
+	}
+	return &csi.Topology{Segments: segments}
+}
+
+func topologyStrings(topologies []*csi.Topology) []string {
+	var result []string
+	for _, topology := range topologies {
+		result = append(result, String(topology))
+	}
+	return result
+}
+
+func TestValidateSegments(t *testing.T) {
+	assert.NoError(t, ValidateSegments(map[string]string{zoneKey: "zone-a", "rack": ""}))
+	err := ValidateSegments(map[string]string{
+		"-invalid":      "a",
+		zoneKey:         "not valid",
+		"a/b/c":         "x",
+		"example.com/x": "ok",
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `topology key "-invalid"`)
+	assert.Contains(t, err.Error(), `topology key "a/b/c"`)
+	assert.Contains(t, err.Error(), `topology key "topology.example.com/zone": value "not valid"`)
+	assert.NotContains(t, err.Error(), "example.com/x\"")
+}
+
+func TestNodeLabels(t *testing.T) {
+	labels := map[string]string{zoneKey: "a", regionKey: "r", "other": "x"}
+	top, err := FromNodeLabels(labels, []string{zoneKey, regionKey})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{zoneKey: "a", regionKey: "r"}, top.Segments)
+	assert.True(t, Matches(top, labels), "matches labels")
+	assert.False(t, Matches(top, map[string]string{zoneKey: "a"}), "missing label")
+	assert.False(t, Matches(top, map[string]string{zoneKey: "b", regionKey: "r"}), "different value")
+
+	_, err = FromNodeLabels(labels, []string{"missing"})
+	assert.EqualError(t, err, `node has no label for topology key "missing"`)
+}
+
+func TestNodeSelectorTerm(t *testing.T) {
+	term := ToNodeSelectorTerm(topology(zoneKey, "a", regionKey, "r"))
+	assert.Equal(t, v1.NodeSelectorTerm{
+		MatchExpressions: []v1.NodeSelectorRequirement{
+			{Key: regionKey, Operator: v1.NodeSelectorOpIn, Values: []string{"r"}},
+			{Key: zoneKey, Operator: v1.NodeSelectorOpIn, Values: []string{"a"}},
+		},
+	}, term)
+	assert.Len(t, ToNodeSelector([]*csi.Topology{topology(zoneKey, "a"), topology(zoneKey, "b")}).NodeSelectorTerms, 2)
+
+	topologies, err := FromNodeSelectorTerm(term)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"topology.example.com/region=r,topology.example.com/zone=a"}, topologyStrings(topologies))
+
+	term.MatchExpressions[1].Values = []string{"a", "b"}
+	topologies, err = FromNodeSelectorTerm(term)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"topology.example.com/region=r,topology.example.com/zone=a",
+		"topology.example.com/region=r,topology.example.com/zone=b",
+	}, topologyStrings(topologies))
+
+	term.MatchExpressions[1].Operator = v1.NodeSelectorOpNotIn
+	_, err = FromNodeSelectorTerm(term)
+	assert.EqualError(t, err, `key "topology.example.com/zone": unsupported operator "NotIn"`)
+
+	_, err = FromNodeSelectorTerm(v1.NodeSelectorTerm{MatchFields: []v1.NodeSelectorRequirement{{Key: "metadata.name"}}})
+	assert.Error(t, err, "fields")
+}
+
+func TestNewTopologyRequirement(t *testing.T) {
+	allowed := []v1.TopologySelectorTerm{
+		{MatchLabelExpressions: []v1.TopologySelectorLabelRequirement{{Key: zoneKey, Values: []string{"a", "b"}}}},
+		{MatchLabelExpressions: []v1.TopologySelectorLabelRequirement{{Key: zoneKey, Values: []string{"c", "a"}}}},
+	}
+
+	testcases := map[string]struct {
+		allowed         []v1.TopologySelectorTerm
+		preferred       *csi.Topology
+		expectRequisite []string
+		expectPreferred []string
+		expectNil       bool
+		expectError     string
+	}{
+		"no constraints": {
+			expectNil: true,
+		},
+		"empty selected node": {
+			preferred: &csi.Topology{},
+			expectNil: true,
+		},
+		"selected node only": {
+			preferred:       topology(zoneKey, "b"),
+			expectRequisite: []string{"topology.example.com/zone=b"},
+			expectPreferred: []string{"topology.example.com/zone=b"},
+		},
+		"allowed only": {
+			allowed:         allowed,
+			expectRequisite: []string{"topology.example.com/zone=a", "topology.example.com/zone=b", "topology.example.com/zone=c"},
+			expectPreferred: []string{"topology.example.com/zone=a", "topology.example.com/zone=b", "topology.example.com/zone=c"},
+		},
+		"allowed and selected node": {
+			allowed:         allowed,
+			preferred:       topology(zoneKey, "c", regionKey, "r"),
+			expectRequisite: []string{"topology.example.com/zone=a", "topology.example.com/zone=b", "topology.example.com/zone=c"},
+			expectPreferred: []string{"topology.example.com/zone=c", "topology.example.com/zone=a", "topology.example.com/zone=b"},
+		},
+		"allowed and empty selected node": {
+			allowed:         allowed,
+			preferred:       &csi.Topology{},
+			expectRequisite: []string{"topology.example.com/zone=a", "topology.example.com/zone=b", "topology.example.com/zone=c"},
+			expectPreferred: []string{"topology.example.com/zone=a", "topology.example.com/zone=b", "topology.example.com/zone=c"},
+		},
+		"selected node not allowed": {
+			allowed:     allowed,
+			preferred:   topology(zoneKey, "d"),
+			expectError: "preferred topology topology.example.com/zone=d is not allowed",
+		},
+		"invalid term": {
+			allowed: []v1.TopologySelectorTerm{
+				{MatchLabelExpressions: []v1.TopologySelectorLabelRequirement{{Key: zoneKey}}},
+			},
+			expectError: `term #0: key "topology.example.com/zone": no values`,
+		},
+		"duplicate key": {
+			allowed: []v1.TopologySelectorTerm{
+				{MatchLabelExpressions: []v1.TopologySelectorLabelRequirement{{Key: zoneKey, Values: []string{"a"}}, {Key: zoneKey, Values: []string{"b"}}}},
+			},
+			expectError: `term #0: key "topology.example.com/zone": used more than once`,
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			requirement, err := NewTopologyRequirement(tc.allowed, tc.preferred)
+			if tc.expectError != "" {
+				assert.EqualError(t, err, tc.expectError)
+				return
+			}
+			require.NoError(t, err)
+			if tc.expectNil {
+				assert.Nil(t, requirement)
+				return
+			}
+			require.NotNil(t, requirement)
+			assert.Equal(t, tc.expectRequisite, topologyStrings(requirement.Requisite), "requisite")
+			assert.Equal(t, tc.expectPreferred, topologyStrings(requirement.Preferred), "preferred")
+		})
+	}
+}
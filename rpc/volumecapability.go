@@ -0,0 +1,143 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rpc
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubernetes-csi/csi-lib-utils/accessmodes"
+	v1 "k8s.io/api/core/v1"
+)
+
+// NewBlockVolumeCapability returns a capability for raw block access.
+func NewBlockVolumeCapability(mode csi.VolumeCapability_AccessMode_Mode) *csi.VolumeCapability {
+	return &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Block{
+			Block: &csi.VolumeCapability_BlockVolume{},
+		},
+		AccessMode: &csi.VolumeCapability_AccessMode{
+			Mode: mode,
+		},
+	}
+}
+
+// NewMountVolumeCapability returns a capability for access through a
+// file system. fsType and mountFlags are optional.
+func NewMountVolumeCapability(mode csi.VolumeCapability_AccessMode_Mode, fsType string, mountFlags []string) *csi.VolumeCapability {
+	return &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Mount{
+			Mount: &csi.VolumeCapability_MountVolume{
+				FsType:     fsType,
+				MountFlags: mountFlags,
+			},
+		},
+		AccessMode: &csi.VolumeCapability_AccessMode{
+			Mode: mode,
+		},
+	}
+}
+
+// NewVolumeCapability builds the capability for a PersistentVolume with
+// the given access modes and volume mode. A nil volume mode is treated
+// like v1.PersistentVolumeFilesystem, as in the PersistentVolume API.
+// fsType and mountFlags are ignored for block volumes.
+//
+// The access modes are mapped with accessmodes.ToCSIAccessMode.
+// supportsSingleNodeMultiWriter must be true if the driver has the
+// SINGLE_NODE_MULTI_WRITER capability.
+func NewVolumeCapability(pvAccessModes []v1.PersistentVolumeAccessMode, volumeMode *v1.PersistentVolumeMode, fsType string, mountFlags []string, supportsSingleNodeMultiWriter bool) (*csi.VolumeCapability, error) {
+	mode, err := accessmodes.ToCSIAccessMode(pvAccessModes, supportsSingleNodeMultiWriter)
+	if err != nil {
+		return nil, err
+	}
+	if volumeMode == nil {
+		return NewMountVolumeCapability(mode, fsType, mountFlags), nil
+	}
+	switch *volumeMode {
+	case v1.PersistentVolumeBlock:
+		return NewBlockVolumeCapability(mode), nil
+	case v1.PersistentVolumeFilesystem:
+		return NewMountVolumeCapability(mode, fsType, mountFlags), nil
+	default:
+		return nil, fmt.Errorf("unsupported volume mode %q", *volumeMode)
+	}
+}
+
+// ValidateVolumeCapabilities checks that each capability is complete and
+// only uses access modes which the driver advertised. The
+// SINGLE_NODE_SINGLE_WRITER and SINGLE_NODE_MULTI_WRITER access modes
+// require the SINGLE_NODE_MULTI_WRITER capability in each of the
+// capability sets that are not nil. Passing nil for a set skips the
+// check for that service.
+//
+// All problems are reported together.
+func ValidateVolumeCapabilities(volumeCaps []*csi.VolumeCapability, controllerCaps ControllerCapabilitySet, nodeCaps NodeCapabilitySet) error {
+	var errs []error
+	for i, volumeCap := range volumeCaps {
+		if err := validateVolumeCapability(volumeCap, controllerCaps, nodeCaps); err != nil {
+			errs = append(errs, fmt.Errorf("volume capability #%d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func validateVolumeCapability(volumeCap *csi.VolumeCapability, controllerCaps ControllerCapabilitySet, nodeCaps NodeCapabilitySet) error {
+	if volumeCap == nil {
+		return errors.New("must not be nil")
+	}
+	var errs []error
+	switch volumeCap.GetAccessType().(type) {
+	case *csi.VolumeCapability_Block, *csi.VolumeCapability_Mount:
+	default:
+		errs = append(errs, errors.New("access type must be either block or mount"))
+	}
+
+	mode := volumeCap.GetAccessMode().GetMode()
+	switch mode {
+	case csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+		csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY,
+		csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY,
+		csi.VolumeCapability_AccessMode_MULTI_NODE_SINGLE_WRITER,
+		csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER:
+	case csi.VolumeCapability_AccessMode_SINGLE_NODE_SINGLE_WRITER,
+		csi.VolumeCapability_AccessMode_SINGLE_NODE_MULTI_WRITER:
+		if controllerCaps != nil && !controllerCaps[csi.ControllerServiceCapability_RPC_SINGLE_NODE_MULTI_WRITER] {
+			errs = append(errs, fmt.Errorf("access mode %s requires the controller capability SINGLE_NODE_MULTI_WRITER", mode))
+		}
+		if nodeCaps != nil && !nodeCaps[csi.NodeServiceCapability_RPC_SINGLE_NODE_MULTI_WRITER] {
+			errs = append(errs, fmt.Errorf("access mode %s requires the node capability SINGLE_NODE_MULTI_WRITER", mode))
+		}
+	case csi.VolumeCapability_AccessMode_UNKNOWN:
+		errs = append(errs, errors.New("access mode must be set"))
+	default:
+		errs = append(errs, fmt.Errorf("unknown access mode %d", mode))
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateVolumeCapabilities checks the capabilities against the
+// controller capabilities if the driver has a controller service,
+// otherwise against the node capabilities. See the
+// ValidateVolumeCapabilities function for details.
+func (c *Client) ValidateVolumeCapabilities(volumeCaps []*csi.VolumeCapability) error {
+	if c.pluginCapabilities[csi.PluginCapability_Service_CONTROLLER_SERVICE] {
+		return ValidateVolumeCapabilities(volumeCaps, c.controllerCapabilities, nil)
+	}
+	return ValidateVolumeCapabilities(volumeCaps, nil, c.nodeCapabilities)
+}
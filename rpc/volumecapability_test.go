@@ -0,0 +1,142 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rpc
+
+import (
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestNewVolumeCapability(t *testing.T) {
+	block := v1.PersistentVolumeBlock
+	filesystem := v1.PersistentVolumeFilesystem
+	invalid := v1.PersistentVolumeMode("invalid")
+
+	testcases := map[string]struct {
+		accessModes                   []v1.PersistentVolumeAccessMode
+		volumeMode                    *v1.PersistentVolumeMode
+		fsType                        string
+		mountFlags                    []string
+		supportsSingleNodeMultiWriter bool
+		expect                        *csi.VolumeCapability
+		expectError                   bool
+	}{
+		"default mode": {
+			accessModes: []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce},
+			fsType:      "ext4",
+			mountFlags:  []string{"noatime"},
+			expect:      NewMountVolumeCapability(csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER, "ext4", []string{"noatime"}),
+		},
+		"filesystem": {
+			accessModes: []v1.PersistentVolumeAccessMode{v1.ReadOnlyMany},
+			volumeMode:  &filesystem,
+			expect:      NewMountVolumeCapability(csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY, "", nil),
+		},
+		"block": {
+			accessModes: []v1.PersistentVolumeAccessMode{v1.ReadWriteMany},
+			volumeMode:  &block,
+			fsType:      "ext4",
+			expect:      NewBlockVolumeCapability(csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER),
+		},
+		"single node multi writer": {
+			accessModes:                   []v1.PersistentVolumeAccessMode{v1.ReadWriteOncePod},
+			volumeMode:                    &block,
+			supportsSingleNodeMultiWriter: true,
+			expect:                        NewBlockVolumeCapability(csi.VolumeCapability_AccessMode_SINGLE_NODE_SINGLE_WRITER),
+		},
+		"invalid access modes": {
+			accessModes: []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce, v1.ReadOnlyMany},
+			expectError: true,
+		},
+		"invalid volume mode": {
+			accessModes: []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce},
+			volumeMode:  &invalid,
+			expectError: true,
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			volumeCap, err := NewVolumeCapability(tc.accessModes, tc.volumeMode, tc.fsType, tc.mountFlags, tc.supportsSingleNodeMultiWriter)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, proto.Equal(tc.expect, volumeCap), "expected %v, got %v", tc.expect, volumeCap)
+		})
+	}
+}
+
+func TestValidateVolumeCapabilities(t *testing.T) {
+	snmw := NewMountVolumeCapability(csi.VolumeCapability_AccessMode_SINGLE_NODE_MULTI_WRITER, "", nil)
+	snw := NewBlockVolumeCapability(csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER)
+	withSNMW := ControllerCapabilitySet{csi.ControllerServiceCapability_RPC_SINGLE_NODE_MULTI_WRITER: true}
+
+	testcases := map[string]struct {
+		volumeCaps     []*csi.VolumeCapability
+		controllerCaps ControllerCapabilitySet
+		nodeCaps       NodeCapabilitySet
+		expectError    string
+	}{
+		"empty": {},
+		"valid": {
+			volumeCaps:     []*csi.VolumeCapability{snw, snmw},
+			controllerCaps: withSNMW,
+		},
+		"unchecked": {
+			volumeCaps: []*csi.VolumeCapability{snmw},
+		},
+		"missing controller capability": {
+			volumeCaps:     []*csi.VolumeCapability{snw, snmw},
+			controllerCaps: ControllerCapabilitySet{},
+			expectError:    "volume capability #1: access mode SINGLE_NODE_MULTI_WRITER requires the controller capability SINGLE_NODE_MULTI_WRITER",
+		},
+		"missing node capability": {
+			volumeCaps:     []*csi.VolumeCapability{snmw},
+			controllerCaps: withSNMW,
+			nodeCaps:       NodeCapabilitySet{csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME: true},
+			expectError:    "volume capability #0: access mode SINGLE_NODE_MULTI_WRITER requires the node capability SINGLE_NODE_MULTI_WRITER",
+		},
+		"incomplete": {
+			volumeCaps: []*csi.VolumeCapability{nil, {}},
+			expectError: `volume capability #0: must not be nil
+volume capability #1: access type must be either block or mount
+access mode must be set`,
+		},
+		"unknown mode": {
+			volumeCaps:  []*csi.VolumeCapability{NewBlockVolumeCapability(1000)},
+			expectError: "volume capability #0: unknown access mode 1000",
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateVolumeCapabilities(tc.volumeCaps, tc.controllerCaps, tc.nodeCaps)
+			if tc.expectError != "" {
+				assert.EqualError(t, err, tc.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
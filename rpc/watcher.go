@@ -0,0 +1,134 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rpc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"k8s.io/klog/v2"
+)
+
+// CapabilityWatcher detects when the capabilities of a CSI driver change
+// while a sidecar is running, for example because the driver was
+// upgraded. It re-runs GetCapabilities each time the connection becomes
+// ready again after it was lost and reconnected, which is the default
+// behavior of connection.Connect for Unix domain sockets. If a
+// callback is registered with connection.OnConnectionLoss, it must
+// allow the reconnect.
+type CapabilityWatcher struct {
+	onChange func(ctx context.Context, oldCaps, newCaps Capabilities, diff CapabilityDiff)
+
+	mutex   sync.RWMutex
+	current *Capabilities
+}
+
+// NewCapabilityWatcher creates a watcher which invokes the callback
+// whenever the capabilities differ from the ones discovered before.
+// The callback is invoked by Run and may decide to exit the process
+// or to reconfigure the sidecar.
+func NewCapabilityWatcher(onChange func(ctx context.Context, oldCaps, newCaps Capabilities, diff CapabilityDiff)) *CapabilityWatcher {
+	return &CapabilityWatcher{
+		onChange: onChange,
+	}
+}
+
+// Capabilities returns the most recently discovered capabilities, nil
+// before Run has discovered them for the first time.
+func (w *CapabilityWatcher) Capabilities() *Capabilities {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.current
+}
+
+// Run discovers the initial capabilities and then watches the connection
+// until the context is canceled. An error is only returned if the initial
+// discovery fails. Later errors are logged and the discovery is retried.
+func (w *CapabilityWatcher) Run(ctx context.Context, conn *grpc.ClientConn) error {
+	logger := klog.FromContext(ctx)
+	caps, err := GetCapabilities(ctx, conn)
+	if err != nil {
+		return err
+	}
+	w.setCapabilities(&caps)
+
+	for {
+		// Wait until the connection is no longer ready...
+		state := conn.GetState()
+		for state == connectivity.Ready {
+			if !conn.WaitForStateChange(ctx, state) {
+				return nil
+			}
+			state = conn.GetState()
+		}
+		logger.V(3).Info("Connection to CSI driver no longer ready, waiting for reconnect", "state", state)
+
+		// ... and ready again. gRPC does not reconnect on its own
+		// while idle, so ask for it.
+		for state != connectivity.Ready {
+			if state == connectivity.Idle {
+				conn.Connect()
+			}
+			if !conn.WaitForStateChange(ctx, state) {
+				return nil
+			}
+			state = conn.GetState()
+		}
+
+		if !w.rediscover(ctx, conn) {
+			return nil
+		}
+	}
+}
+
+// rediscover retrieves the capabilities after a reconnect, with retries.
+// It returns false if the context was canceled.
+func (w *CapabilityWatcher) rediscover(ctx context.Context, conn *grpc.ClientConn) bool {
+	logger := klog.FromContext(ctx)
+	for {
+		caps, err := GetCapabilities(ctx, conn)
+		if err == nil {
+			oldCaps := w.Capabilities()
+			w.setCapabilities(&caps)
+			diff := DiffCapabilities(*oldCaps, caps)
+			if diff.Empty() {
+				logger.V(3).Info("CSI driver capabilities unchanged after reconnect")
+			} else {
+				logger.Info("CSI driver capabilities changed after reconnect", "diff", diff)
+				if w.onChange != nil {
+					w.onChange(ctx, *oldCaps, caps, diff)
+				}
+			}
+			return true
+		}
+		logger.Error(err, "Failed to get CSI driver capabilities after reconnect, will retry")
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(probeInterval):
+		}
+	}
+}
+
+func (w *CapabilityWatcher) setCapabilities(caps *Capabilities) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.current = caps
+}
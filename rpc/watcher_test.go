@@ -0,0 +1,123 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rpc
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubernetes-csi/csi-lib-utils/connection"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/klog/v2/ktesting"
+)
+
+func TestCapabilityWatcher(t *testing.T) {
+	tmp := tmpDir(t)
+	defer os.RemoveAll(tmp)
+	identity := &fakeIdentityServer{
+		getPluginCapabilitiesResponse: pluginCapabilitiesResponse(csi.PluginCapability_Service_CONTROLLER_SERVICE),
+	}
+	controller := &fakeControllerServer{
+		controllerGetCapabilitiesResponse: controllerCapabilitiesResponse(
+			csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME,
+			csi.ControllerServiceCapability_RPC_LIST_VOLUMES,
+		),
+	}
+	_, stopServer := startServer(t, tmp, identity, controller, nil, nil)
+	defer func() {
+		stopServer()
+	}()
+
+	_, ctx := ktesting.NewTestContext(t)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	diffs := make(chan CapabilityDiff, 10)
+	watcher := NewCapabilityWatcher(func(ctx context.Context, oldCaps, newCaps Capabilities, diff CapabilityDiff) {
+		diffs <- diff
+	})
+	addr := tmp + "/" + serverSock
+	conn, err := connection.Connect(ctx, addr, nil, connection.OnConnectionLoss(func(context.Context) bool { return true }))
+	require.NoError(t, err, "connect")
+	defer conn.Close()
+
+	done := make(chan error)
+	go func() {
+		done <- watcher.Run(ctx, conn)
+	}()
+	require.Eventually(t, func() bool { return watcher.Capabilities() != nil }, 10*time.Second, 10*time.Millisecond, "initial discovery")
+	assert.Equal(t, ControllerCapabilitySet{
+		csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME: true,
+		csi.ControllerServiceCapability_RPC_LIST_VOLUMES:         true,
+	}, watcher.Capabilities().Controller)
+
+	// Simulate a driver upgrade.
+	stopServer()
+	identity = &fakeIdentityServer{
+		getPluginCapabilitiesResponse: pluginCapabilitiesResponse(csi.PluginCapability_Service_CONTROLLER_SERVICE, csi.PluginCapability_Service_GROUP_CONTROLLER_SERVICE),
+	}
+	controller = &fakeControllerServer{
+		controllerGetCapabilitiesResponse: controllerCapabilitiesResponse(
+			csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME,
+			csi.ControllerServiceCapability_RPC_GET_CAPACITY,
+		),
+	}
+	groupCtrl := &fakeGroupControllerServer{
+		groupControllerGetCapabilitiesResponse: &csi.GroupControllerGetCapabilitiesResponse{},
+	}
+	_, stopServer = startServer(t, tmp, identity, controller, groupCtrl, nil)
+
+	select {
+	case diff := <-diffs:
+		assert.Equal(t, CapabilityDiff{
+			PluginAdded:       []csi.PluginCapability_Service_Type{csi.PluginCapability_Service_GROUP_CONTROLLER_SERVICE},
+			ControllerAdded:   []csi.ControllerServiceCapability_RPC_Type{csi.ControllerServiceCapability_RPC_GET_CAPACITY},
+			ControllerRemoved: []csi.ControllerServiceCapability_RPC_Type{csi.ControllerServiceCapability_RPC_LIST_VOLUMES},
+		}, diff)
+	case <-time.After(30 * time.Second):
+		t.Fatal("timed out waiting for capability change")
+	}
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestDiffCapabilities(t *testing.T) {
+	oldCaps := Capabilities{
+		Plugin: PluginCapabilitySet{csi.PluginCapability_Service_CONTROLLER_SERVICE: true},
+		Controller: ControllerCapabilitySet{
+			csi.ControllerServiceCapability_RPC_LIST_VOLUMES:         true,
+			csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME: true,
+		},
+	}
+	assert.True(t, DiffCapabilities(oldCaps, oldCaps).Empty(), "same capabilities")
+
+	newCaps := Capabilities{
+		Plugin: PluginCapabilitySet{},
+	}
+	assert.Equal(t, CapabilityDiff{
+		PluginRemoved: []csi.PluginCapability_Service_Type{csi.PluginCapability_Service_CONTROLLER_SERVICE},
+		ControllerRemoved: []csi.ControllerServiceCapability_RPC_Type{
+			csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME,
+			csi.ControllerServiceCapability_RPC_LIST_VOLUMES,
+		},
+	}, DiffCapabilities(oldCaps, newCaps))
+}